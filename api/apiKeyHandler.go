@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/auth"
+	"finalproject/data"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type apiKeyRequest struct {
+	Name       string     `json:"name"`
+	Roles      []string   `json:"roles"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type rotationRequest struct {
+	GracePeriodSeconds int `json:"grace_period_seconds"`
+}
+
+// apiKeyWithSecret is the response of creations and rotations, the only ones showing the secret.
+type apiKeyWithSecret struct {
+	data.APIKey
+	Key string `json:"key"`
+}
+
+// maxRotationGracePeriod bounds how long a replaced secret stays valid.
+const maxRotationGracePeriod = 7 * 24 * time.Hour
+
+func getAPIKeyRepo(w http.ResponseWriter, r *http.Request) (*data.APIKeyRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+	return data.NewAPIKeyRepository(store), nil
+}
+
+func validateAPIKey(request apiKeyRequest) error {
+	if request.Name == "" {
+		return errors.New("missing name")
+	}
+	if len(request.Scopes) == 0 {
+		return errors.New("an API key needs at least one scope")
+	}
+	for _, scope := range request.Scopes {
+		if err := auth.ValidateAPIKeyScope(scope); err != nil {
+			return err
+		}
+	}
+	for _, role := range request.Roles {
+		if !auth.IsKnownRole(role) {
+			return errors.New("unknown role " + role)
+		}
+	}
+	for _, entry := range request.AllowedIPs {
+		if err := auth.ValidateAllowedIP(entry); err != nil {
+			return err
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	repo, err := getAPIKeyRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	var request apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if err := validateAPIKey(request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := data.APIKey{
+		Name:       request.Name,
+		Roles:      append([]string{}, request.Roles...),
+		Scopes:     request.Scopes,
+		AllowedIPs: append([]string{}, request.AllowedIPs...),
+		ExpiresAt:  request.ExpiresAt,
+	}
+	if user, ok := currentUser(r); ok {
+		key.CreatedBy = user.ID
+	}
+
+	created, secret, err := repo.Create(key)
+	if err != nil {
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api-keys/%d", created.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(apiKeyWithSecret{APIKey: created, Key: secret})
+}
+
+func GetAllAPIKeys(w http.ResponseWriter, r *http.Request) {
+	repo, err := getAPIKeyRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	keys, err := repo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to retrieve API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+func GetAPIKeyById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getAPIKeyRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	key, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrAPIKeyNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
+
+// RotateAPIKey gives a key a new secret. The old secret keeps working for the requested grace period.
+func RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	repo, err := getAPIKeyRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	var request rotationRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+	gracePeriod := time.Duration(request.GracePeriodSeconds) * time.Second
+	if gracePeriod < 0 || gracePeriod > maxRotationGracePeriod {
+		http.Error(w, "grace_period_seconds must be between 0 and 604800", http.StatusBadRequest)
+		return
+	}
+
+	rotated, secret, err := repo.Rotate(id, gracePeriod)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAPIKeyNotFound):
+			http.Error(w, "API key not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrAPIKeyRevoked):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to rotate API key", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(apiKeyWithSecret{APIKey: rotated, Key: secret})
+}
+
+// RevokeAPIKey disables a key at once, its previous secret included.
+func RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	repo, err := getAPIKeyRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.Revoke(id); err != nil {
+		if errors.Is(err, data.ErrAPIKeyNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -6,7 +6,6 @@ import (
 	"finalproject/data"
 	"net/http"
 	"strconv"
-	"strings"
 )
 
 func getAuthorRepoFromFactory(w http.ResponseWriter, r *http.Request) (data.IDAO[data.Author], error) {
@@ -47,8 +46,11 @@ func CreateAuthor(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var author data.Author
-	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeJSON(w, r, &author) {
+		return
+	}
+	if errs := validateAuthor(author); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
 		return
 	}
 
@@ -69,8 +71,7 @@ func GetAuthorById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/authors/")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "Invalid author ID", http.StatusBadRequest)
 		return
@@ -92,16 +93,18 @@ func UpdateAuthorById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/authors/")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "Invalid author ID", http.StatusBadRequest)
 		return
 	}
 
 	var updatedAuthor data.Author
-	if err := json.NewDecoder(r.Body).Decode(&updatedAuthor); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeJSON(w, r, &updatedAuthor) {
+		return
+	}
+	if errs := validateAuthor(updatedAuthor); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
 		return
 	}
 
@@ -121,8 +124,7 @@ func DeleteAuthorById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/authors/")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "Invalid author ID", http.StatusBadRequest)
 		return
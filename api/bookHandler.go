@@ -24,6 +24,34 @@ func getBookRepoFromFactory(w http.ResponseWriter, r *http.Request) (data.IDAO[d
 	return repo, nil
 }
 
+// validBook answers 422 when the book is invalid.
+func validBook(w http.ResponseWriter, r *http.Request, book data.Book) bool {
+	store, _ := r.Context().Value("memoryStore").(*data.DBTemplate)
+	errs, err := validateBook(store, book)
+	if err != nil {
+		http.Error(w, "Failed to validate book", http.StatusInternalServerError)
+		return false
+	}
+	if len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return false
+	}
+	return true
+}
+
+// bookReferenceProblem answers 422 when the author or publisher was deleted after the book was validated.
+func bookReferenceProblem(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, data.ErrAuthorNotFound):
+		writeValidationProblem(w, r, []FieldError{{Field: "author.id", Code: "not_found", Message: "no author has this id"}})
+	case errors.Is(err, data.ErrPublisherNotFound):
+		writeValidationProblem(w, r, []FieldError{{Field: "publisher.id", Code: "not_found", Message: "no publisher has this id"}})
+	default:
+		return false
+	}
+	return true
+}
+
 func GetAllBooks(w http.ResponseWriter, r *http.Request) {
 	repo, err := getBookRepoFromFactory(w, r)
 	if err != nil {
@@ -33,20 +61,28 @@ func GetAllBooks(w http.ResponseWriter, r *http.Request) {
 	title := r.URL.Query().Get("title")
 	author := r.URL.Query().Get("author")
 	genre := r.URL.Query().Get("genre")
+	publisher := r.URL.Query().Get("publisher")
+	sortBy := r.URL.Query().Get("sort")
 
 	var books []data.Book
 
-	if title != "" || author != "" || genre != "" {
+	if title != "" || author != "" || genre != "" || publisher != "" || sortBy != "" {
 		searchCriteria := data.SearchCriteria{
 			Title: title,
 			AuthorName: author,
 			Genre: genre,
+			Publisher: publisher,
+			SortBy: sortBy,
 		}
 		books, err = repo.(*data.BookRepository).GetBookBySearchCriteria(searchCriteria)
 	} else {
 		books, err = repo.GetAll()
 	}
 
+	if errors.Is(err, data.ErrUnknownBookSort) {
+		http.Error(w, "Invalid sort, must be one of "+strings.Join(data.BookSortKeys(), ", "), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve books" + err.Error(), http.StatusInternalServerError)
 		return
@@ -63,14 +99,15 @@ func CreateBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var book data.Book
-	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeJSON(w, r, &book) || !validBook(w, r, book) {
 		return
 	}
 
 	createdBook, err := repo.Create(book)
 	if err != nil {
-		http.Error(w, "Failed to create book" + err.Error(), http.StatusInternalServerError)
+		if !bookReferenceProblem(w, r, err) {
+			http.Error(w, "Failed to create book" + err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -85,8 +122,7 @@ func GetBookById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/books/")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "Invalid book ID", http.StatusBadRequest)
 		return
@@ -108,22 +144,22 @@ func UpdateBookById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/books/")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "Invalid book ID", http.StatusBadRequest)
 		return
 	}
 
 	var updatedBook data.Book
-	if err := json.NewDecoder(r.Body).Decode(&updatedBook); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+	if !decodeJSON(w, r, &updatedBook) || !validBook(w, r, updatedBook) {
 		return
 	}
 
 	book, err := repo.Update(id, updatedBook)
 	if err != nil {
-		http.Error(w, "Failed to update book", http.StatusInternalServerError)
+		if !bookReferenceProblem(w, r, err) {
+			http.Error(w, "Failed to update book", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -137,17 +173,39 @@ func DeleteBookById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/books/")
-	id, err := strconv.Atoi(idStr)
+	blobs, err := getBlobStoreFromContext(w, r)
+	if err != nil {
+		return
+	}
+	store := r.Context().Value("memoryStore").(*data.DBTemplate)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "Invalid book ID", http.StatusBadRequest)
 		return
 	}
 
-	if err := repo.Delete(id); err != nil {
+	// The cover row goes away with the book, its blobs have to be removed by hand.
+	cover, err := data.NewCoverRepository(store).GetByBookId(id)
+	if err != nil && !errors.Is(err, data.ErrCoverNotFound) {
 		http.Error(w, "Failed to delete book", http.StatusInternalServerError)
 		return
 	}
 
+	if err := repo.Delete(id); err != nil {
+		switch {
+		case errors.Is(err, data.ErrBookNotFound):
+			http.Error(w, "Book not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrBookSold):
+			http.Error(w, "Book was ordered, it can't be deleted", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to delete book", http.StatusInternalServerError)
+		}
+		return
+	}
+	if cover.ETag != "" {
+		deleteBlobs(r, blobs, []string{coverKey(id, "original", cover.ETag), coverKey(id, "thumbnail", cover.ThumbnailETag)})
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
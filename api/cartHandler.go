@@ -0,0 +1,155 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"net/http"
+	"strconv"
+)
+
+const maxCartLineQuantity = 100
+
+type cartItemRequest struct {
+	BookID   int `json:"book_id"`
+	Quantity int `json:"quantity"`
+}
+
+func getCartRepoFromContext(w http.ResponseWriter, r *http.Request) (*data.CartRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+	return data.NewCartRepository(store), nil
+}
+
+// cartSessionKey derives the cart key from the session of the access token, so the cart survives token refreshes.
+func cartSessionKey(r *http.Request) string {
+	claims, _ := currentClaims(r)
+	sum := sha256.Sum256([]byte(claims.SessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeCart(w http.ResponseWriter, repo *data.CartRepository, sessionKey string) {
+	cart, err := repo.Get(sessionKey)
+	if err != nil {
+		http.Error(w, "Failed to retrieve cart", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
+
+func cartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrBookNotFound):
+		http.Error(w, "Book not found", http.StatusNotFound)
+	case errors.Is(err, data.ErrCartItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to update cart", http.StatusInternalServerError)
+	}
+}
+
+func GetCart(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCartRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+	writeCart(w, repo, cartSessionKey(r))
+}
+
+func ClearCart(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCartRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := repo.Clear(cartSessionKey(r)); err != nil {
+		http.Error(w, "Failed to clear cart", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func AddCartItem(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCartRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var item cartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if item.Quantity == 0 {
+		item.Quantity = 1
+	}
+	if item.Quantity < 1 || item.Quantity > maxCartLineQuantity {
+		http.Error(w, "Quantity must be between 1 and 100", http.StatusBadRequest)
+		return
+	}
+
+	sessionKey := cartSessionKey(r)
+	if err := repo.AddItem(sessionKey, item.BookID, item.Quantity); err != nil {
+		cartError(w, err)
+		return
+	}
+	writeCart(w, repo, sessionKey)
+}
+
+func UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCartRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	bookID, err := strconv.Atoi(r.PathValue("bookId"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	var item cartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if item.Quantity < 0 || item.Quantity > maxCartLineQuantity {
+		http.Error(w, "Quantity must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+
+	sessionKey := cartSessionKey(r)
+	if err := repo.UpdateQuantity(sessionKey, bookID, item.Quantity); err != nil {
+		cartError(w, err)
+		return
+	}
+	writeCart(w, repo, sessionKey)
+}
+
+func RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCartRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	bookID, err := strconv.Atoi(r.PathValue("bookId"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	sessionKey := cartSessionKey(r)
+	if err := repo.RemoveItem(sessionKey, bookID); err != nil {
+		cartError(w, err)
+		return
+	}
+	writeCart(w, repo, sessionKey)
+}
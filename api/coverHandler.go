@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"finalproject/imaging"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxCoverSize       = 5 << 20
+	maxCoverDimension  = 6000
+	thumbnailMaxWidth  = 300
+	thumbnailMaxHeight = 450
+)
+
+// Only formats the standard library can decode are accepted, since every upload is thumbnailed.
+var allowedCoverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+func getBlobStoreFromContext(w http.ResponseWriter, r *http.Request) (data.BlobStore, error) {
+	blobs, ok := r.Context().Value("blobStore").(data.BlobStore)
+	if !ok || blobs == nil {
+		http.Error(w, "Blob store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("blob store not found in context")
+	}
+	return blobs, nil
+}
+
+// coverKey names the blob of a cover variant after its ETag, so a replacement is written next to
+// the cover it replaces instead of over it.
+func coverKey(bookID int, variant, etag string) string {
+	return fmt.Sprintf("covers/%d/%s-%s", bookID, variant, strings.Trim(etag, `"`))
+}
+
+func blobETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func UploadBookCover(w http.ResponseWriter, r *http.Request) {
+	bookRepo, err := getBookRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+	blobs, err := getBlobStoreFromContext(w, r)
+	if err != nil {
+		return
+	}
+	store := r.Context().Value("memoryStore").(*data.DBTemplate)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := bookRepo.GetById(id); err != nil {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+
+	// Leave some room for the multipart envelope around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxCoverSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Cover exceeds the maximum size of 5 MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("cover")
+	if err != nil {
+		http.Error(w, "Missing cover file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxCoverSize+1))
+	if err != nil {
+		http.Error(w, "Failed to read cover", http.StatusBadRequest)
+		return
+	}
+	if len(content) > maxCoverSize {
+		http.Error(w, "Cover exceeds the maximum size of 5 MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The client supplied Content-Type is ignored, the actual bytes decide.
+	contentType := http.DetectContentType(content)
+	if !allowedCoverTypes[contentType] {
+		http.Error(w, "Unsupported cover type "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		http.Error(w, "Cover is not a valid image", http.StatusBadRequest)
+		return
+	}
+	if config.Width > maxCoverDimension || config.Height > maxCoverDimension {
+		http.Error(w, "Cover dimensions exceed 6000x6000 pixels", http.StatusBadRequest)
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		http.Error(w, "Cover is not a valid image", http.StatusBadRequest)
+		return
+	}
+
+	var thumbnail bytes.Buffer
+	thumbnailType := "image/png"
+	resized := imaging.Thumbnail(img, thumbnailMaxWidth, thumbnailMaxHeight)
+	if contentType == "image/jpeg" {
+		thumbnailType = "image/jpeg"
+		err = jpeg.Encode(&thumbnail, resized, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&thumbnail, resized)
+	}
+	if err != nil {
+		http.Error(w, "Failed to generate thumbnail", http.StatusInternalServerError)
+		return
+	}
+
+	covers := data.NewCoverRepository(store)
+	previous, err := covers.GetByBookId(id)
+	if err != nil && !errors.Is(err, data.ErrCoverNotFound) {
+		http.Error(w, "Failed to read cover", http.StatusInternalServerError)
+		return
+	}
+
+	cover := data.BookCover{
+		BookID:               id,
+		ContentType:          contentType,
+		Size:                 int64(len(content)),
+		Width:                config.Width,
+		Height:               config.Height,
+		ETag:                 blobETag(content),
+		ThumbnailContentType: thumbnailType,
+		ThumbnailSize:        int64(thumbnail.Len()),
+		ThumbnailETag:        blobETag(thumbnail.Bytes()),
+		UpdatedAt:            time.Now().UTC().Truncate(time.Second),
+	}
+	// Blobs the previous cover still uses are never deleted, which only happens when the same
+	// image is uploaded again.
+	var written, stale []string
+	for _, variant := range []struct {
+		name, etag, previousETag string
+		content                  []byte
+	}{
+		{"original", cover.ETag, previous.ETag, content},
+		{"thumbnail", cover.ThumbnailETag, previous.ThumbnailETag, thumbnail.Bytes()},
+	} {
+		key := coverKey(id, variant.name, variant.etag)
+		if err := blobs.Put(key, bytes.NewReader(variant.content)); err != nil {
+			deleteBlobs(r, blobs, written)
+			http.Error(w, "Failed to store "+variant.name, http.StatusInternalServerError)
+			return
+		}
+		if variant.previousETag == variant.etag {
+			continue
+		}
+		written = append(written, key)
+		if variant.previousETag != "" {
+			stale = append(stale, coverKey(id, variant.name, variant.previousETag))
+		}
+	}
+
+	replaced, err := covers.Save(cover)
+	if err != nil {
+		deleteBlobs(r, blobs, written)
+		http.Error(w, "Failed to save cover", http.StatusInternalServerError)
+		return
+	}
+	deleteBlobs(r, blobs, stale)
+
+	w.Header().Set("Content-Type", "application/json")
+	if replaced {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(cover)
+}
+
+// deleteBlobs removes blobs no cover points to anymore. Failures are only logged, a leftover blob
+// is never served.
+func deleteBlobs(r *http.Request, blobs data.BlobStore, keys []string) {
+	for _, key := range keys {
+		if err := blobs.Delete(key); err != nil && !errors.Is(err, data.ErrBlobNotFound) {
+			loggerFrom(r).Warn("failed to delete cover blob", "key", key, "error", err)
+		}
+	}
+}
+
+// GetBookCover serves the original cover, or the thumbnail with ?size=thumbnail.
+// http.ServeContent takes care of Range, If-None-Match and If-Modified-Since.
+func GetBookCover(w http.ResponseWriter, r *http.Request) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return
+	}
+	blobs, err := getBlobStoreFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	cover, err := data.NewCoverRepository(store).GetByBookId(id)
+	if err != nil {
+		http.Error(w, "Cover not found", http.StatusNotFound)
+		return
+	}
+
+	variant, contentType, etag := "original", cover.ContentType, cover.ETag
+	if r.URL.Query().Get("size") == "thumbnail" {
+		variant, contentType, etag = "thumbnail", cover.ThumbnailContentType, cover.ThumbnailETag
+	}
+
+	blob, err := blobs.Open(coverKey(id, variant, etag))
+	if err != nil {
+		if errors.Is(err, data.ErrBlobNotFound) {
+			http.Error(w, "Cover not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to read cover", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeContent(w, r, "", cover.UpdatedAt, blob)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"net/http"
+	"strconv"
+)
+
+func getCustomerRepoFromFactory(w http.ResponseWriter, r *http.Request) (data.IDAO[data.Customer], error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+
+	repo, err := data.GetDAO[data.Customer]("customer", store)
+	if err != nil {
+		http.Error(w, "Failed to retrieve customer repository", http.StatusInternalServerError)
+		return nil, err
+	}
+	return repo, nil
+}
+
+func CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCustomerRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	var customer data.Customer
+	if !decodeJSON(w, r, &customer) {
+		return
+	}
+	if errs := validateCustomer(customer); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+
+	created, err := repo.Create(customer)
+	if err != nil {
+		if errors.Is(err, data.ErrCustomerEmailTaken) {
+			http.Error(w, "Email is already used by another customer", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create customer", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func GetCustomerById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCustomerRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrCustomerNotFound) {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve customer", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
+}
+
+func UpdateCustomerById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getCustomerRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var updated data.Customer
+	if !decodeJSON(w, r, &updated) {
+		return
+	}
+	if errs := validateCustomer(updated); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+
+	customer, err := repo.Update(id, updated)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCustomerNotFound):
+			http.Error(w, "Customer not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrCustomerEmailTaken):
+			http.Error(w, "Email is already used by another customer", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to update customer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"finalproject/data"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	startedAt = time.Now()
+	// draining is set once the server is shutting down, so load balancers stop sending requests.
+	draining atomic.Bool
+)
+
+// StartDraining makes /readyz fail from now on. Call it on shutdown, before the server stops
+// accepting connections, and leave the load balancers a few probes to notice it.
+func StartDraining() {
+	draining.Store(true)
+}
+
+// checkResult is what a probe learns of a check: its name and whether it passed. Why a check
+// failed is only logged, the probes are anonymous.
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type healthResponse struct {
+	Status        string        `json:"status"`
+	UptimeSeconds int64         `json:"uptime_seconds"`
+	Checks        []checkResult `json:"checks,omitempty"`
+}
+
+// runCheck runs a check and logs why it failed.
+func runCheck(r *http.Request, name string, check func() error) checkResult {
+	start := time.Now()
+	if err := check(); err != nil {
+		loggerFrom(r).Warn("readiness check failed", "check", name, "error", err, "duration_ms", time.Since(start).Milliseconds())
+		return checkResult{Name: name, Status: "fail"}
+	}
+	return checkResult{Name: name, Status: "ok"}
+}
+
+func writeHealth(w http.ResponseWriter, response healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if response.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetHealth answers as long as the process serves requests. It checks nothing else, so an
+// orchestrator doesn't restart the server because the database is down.
+func GetHealth(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, healthResponse{Status: "ok", UptimeSeconds: int64(time.Since(startedAt).Seconds())})
+}
+
+// GetReadiness checks what serving requests depends on: the database and its schema version, the
+// report generator and the reports directory. It fails while the server drains on shutdown.
+func GetReadiness(w http.ResponseWriter, r *http.Request) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok {
+		http.Error(w, "Failed to get memory store", http.StatusInternalServerError)
+		return
+	}
+
+	checks := []checkResult{
+		runCheck(r, "shutdown", func() error {
+			if draining.Load() {
+				return fmt.Errorf("server is shutting down")
+			}
+			return nil
+		}),
+		runCheck(r, "database", store.Ping),
+		runCheck(r, "schema", func() error {
+			version, err := store.GetSchemaVersion()
+			if err != nil {
+				return err
+			}
+			if version != data.SchemaVersion {
+				return fmt.Errorf("database schema is at version %d, expected %d", version, data.SchemaVersion)
+			}
+			return nil
+		}),
+		runCheck(r, "report_generator", func() error {
+			heartbeat := data.ReportGeneratorHeartbeat()
+			if heartbeat.IsZero() {
+				return fmt.Errorf("report generator not started")
+			}
+			// A run may take a while, two intervals without news means it is stuck.
+			if age := time.Since(heartbeat); age > 2*data.ReportInterval() {
+				return fmt.Errorf("report generator last seen %s ago", age.Round(time.Second))
+			}
+			return nil
+		}),
+		runCheck(r, "reports_dir", func() error {
+			if err := data.ReportsWritable(); err != nil {
+				return fmt.Errorf("%s: %w", data.ReportsDir(), err)
+			}
+			return nil
+		}),
+	}
+
+	response := healthResponse{Status: "ok", UptimeSeconds: int64(time.Since(startedAt).Seconds()), Checks: checks}
+	for _, check := range checks {
+		if check.Status != "ok" {
+			response.Status = "fail"
+		}
+	}
+	writeHealth(w, response)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"finalproject/invoicing"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// invoiceFormat picks the invoice representation from the format query parameter, then from the Accept header.
+func invoiceFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/pdf"):
+		return "pdf"
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	}
+	return "html"
+}
+
+// GetOrderInvoice returns the invoice of an order as HTML, PDF or JSON, issuing it on the first request.
+func GetOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeOrder(w, r, orderID) {
+		return
+	}
+
+	format := invoiceFormat(r)
+	if format != "html" && format != "pdf" && format != "json" {
+		http.Error(w, "Format must be html, pdf or json", http.StatusBadRequest)
+		return
+	}
+
+	invoice, err := data.NewInvoiceRepository(store).IssueForOrder(orderID)
+	if err != nil {
+		var notAvailableErr *data.InvoiceNotAvailableError
+		switch {
+		case errors.As(err, &notAvailableErr):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, data.ErrOrderNotFound):
+			http.Error(w, "Order not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch format {
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", invoice.Number+".pdf"))
+		w.Write(invoicing.PDF(invoice))
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoice)
+	default:
+		// The page is rendered in memory first so a template error does not leave a half written response.
+		var page bytes.Buffer
+		if err := invoicing.HTML(&page, invoice); err != nil {
+			http.Error(w, "Failed to render invoice", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		page.WriteTo(w)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"finalproject/metrics"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	requestsTotal    = metrics.NewCounterVec("http_requests_total", "HTTP requests by route, method and status.", "route", "method", "status")
+	requestDuration  = metrics.NewHistogramVec("http_request_duration_seconds", "Latency of the HTTP requests by route, method and status.", metrics.DefaultBuckets, "route", "method", "status")
+	requestsInFlight = metrics.NewGauge("http_requests_in_flight", "HTTP requests being served.")
+)
+
+// Metrics counts and times the requests served by the mux. It wraps the mux itself rather
+// than each route: the mux sets r.Pattern, the route label, on the request it is given.
+// Requests matching no route are labelled "unmatched", so unknown paths can't add series.
+func Metrics(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+
+		lrw := NewLoggingResponseWriter(w)
+		mux.ServeHTTP(lrw, r)
+
+		route := routePath(r.Pattern)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(lrw.statusCode)
+		method := metricMethod(r.Method)
+		requestsTotal.With(route, method, status).Inc()
+		requestDuration.With(route, method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePath is the path of a route pattern, without its method or host ("GET /books/{id}" gives "/books/{id}").
+func routePath(pattern string) string {
+	if i := strings.Index(pattern, "/"); i > 0 {
+		return pattern[i:]
+	}
+	return pattern
+}
+
+// metricMethod bounds the method label to the standard methods.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
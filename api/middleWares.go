@@ -2,138 +2,353 @@ package api
 
 import (
 	"context"
+	"errors"
+	"finalproject/auth"
 	"finalproject/data"
-	"log"
+	"finalproject/logging"
+	"finalproject/mail"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var tokenStore []string
+// signingKeys signs the access tokens issued at login and verifies the tokens of incoming requests.
+var signingKeys *auth.KeyManager
 
-func BooksRouter(w http.ResponseWriter, r *http.Request){
-	if r.Method == http.MethodGet {
-		GetAllBooks(w, r)
-	} else if r.Method == http.MethodPost {
-		CreateBook(w, r)
-	} else {
-		http.Error(w, "Invalid request method", http.StatusBadRequest)
-	}
+// sessions keeps the login sessions, every authenticated request must belong to an active one.
+var sessions data.SessionStore
+
+// UseSessionStore sets the store of the login sessions, it must be called before serving requests.
+func UseSessionStore(store data.SessionStore) {
+	sessions = store
+}
+
+// apiKeys checks the API keys of integrations, which authenticate without logging in.
+var apiKeys *data.APIKeyRepository
+
+// UseAPIKeys sets the repository of the API keys, it must be called before serving requests.
+func UseAPIKeys(repo *data.APIKeyRepository) {
+	apiKeys = repo
 }
 
+// UseSigningKeys sets the keys of the access tokens, it must be called before serving requests.
+func UseSigningKeys(keys *auth.KeyManager) {
+	signingKeys = keys
+}
 
-func BooksPathParamRouter(w http.ResponseWriter, r *http.Request){
-	if r.Method == http.MethodGet {
-		GetBookById(w, r)
-	} else if r.Method == http.MethodPut {
-		UpdateBookById(w, r)
-	} else if r.Method == http.MethodDelete {
-		DeleteBookById(w, r)
-	} else {
-		http.Error(w, "Invalid request method", http.StatusBadRequest)
+// Authenticate verifies the bearer access token and that its session is still active, or the API key
+// sent as a bearer token or in the X-API-Key header. Tokens without the write scope may only be used for safe methods.
+func Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credential := bearerToken(r)
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			credential = key
+		}
+		if credential == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		_, span := tracer.Start(r.Context(), "authenticate")
+		var claims auth.Claims
+		var ok bool
+		if strings.HasPrefix(credential, data.APIKeyMarker) {
+			claims, ok = authenticateAPIKey(w, r, credential)
+		} else {
+			claims, ok = authenticateAccessToken(w, r, credential)
+		}
+		span.End()
+		if !ok {
+			return
+		}
+
+		safe := r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+		if !safe && !claims.HasScope(auth.ScopeWrite) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="write"`)
+			http.Error(w, "Forbidden: the token lacks the write scope", http.StatusForbidden)
+			return
+		}
+
+		if info, ok := r.Context().Value("requestInfo").(*requestInfo); ok {
+			info.subject = claims.Subject
+		}
+		annotateSpan(r, attribute.String("enduser.id", claims.Subject))
+		ctx := context.WithValue(r.Context(), "claims", claims)
+		ctx = logging.WithLogger(ctx, loggerFrom(r).With("subject", claims.Subject))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func authenticateAccessToken(w http.ResponseWriter, r *http.Request, token string) (auth.Claims, bool) {
+	claims, err := signingKeys.Verify(token)
+	if err != nil {
+		// Why the token was refused stays in the log, it would tell a forger what to fix.
+		loggerFrom(r).Info("access token rejected", "error", err)
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return auth.Claims{}, false
+	}
+
+	if _, err := sessions.Touch(claims.SessionID, clientIP(r), time.Now().UTC()); err != nil {
+		if errors.Is(err, data.ErrSessionNotFound) || errors.Is(err, data.ErrSessionInactive) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "Unauthorized: "+data.ErrSessionInactive.Error(), http.StatusUnauthorized)
+			return auth.Claims{}, false
+		}
+		http.Error(w, "Failed to check session", http.StatusInternalServerError)
+		return auth.Claims{}, false
 	}
+	return claims, true
 }
 
+// authenticateAPIKey checks an API key, its IP allowlist and that the route is within its scopes.
+// The key is given claims of its own: its roles, and a subject and session naming the key.
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, secret string) (auth.Claims, bool) {
+	now := time.Now().UTC()
+	key, err := apiKeys.Verify(secret, now)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidAPIKey) {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return auth.Claims{}, false
+		}
+		http.Error(w, "Failed to check API key", http.StatusInternalServerError)
+		return auth.Claims{}, false
+	}
+
+	ip := clientIP(r)
+	if !auth.IPAllowed(key.AllowedIPs, ip) {
+		http.Error(w, "Forbidden: the API key can't be used from "+ip, http.StatusForbidden)
+		return auth.Claims{}, false
+	}
+	if !auth.APIKeyScopeAllows(key.Scopes, r.Method, routePath(r.Pattern)) {
+		http.Error(w, fmt.Sprintf("Forbidden: the API key is not scoped for %s %s", r.Method, routePath(r.Pattern)), http.StatusForbidden)
+		return auth.Claims{}, false
+	}
+	if err := apiKeys.RecordUse(key, ip, now); err != nil {
+		loggerFrom(r).Error("failed to record API key use", "api_key_id", key.ID, "error", err)
+	}
+
+	subject := "apikey:" + strconv.Itoa(key.ID)
+	return auth.Claims{
+		Issuer:    auth.Issuer,
+		Subject:   subject,
+		SessionID: subject,
+		Roles:     key.Roles,
+		Scope:     auth.DefaultScope,
+	}, true
+}
 
-func AuthorsRouter(w http.ResponseWriter, r *http.Request){
-	if r.Method == http.MethodGet {
-		GetAllAuthors(w, r)
-	} else if r.Method == http.MethodPost {
-		CreateAuthor(w, r)
-	} else {
-		http.Error(w, "Invalid request method", http.StatusBadRequest)
+// RequirePermission refuses the requests whose roles lack the permission of the route.
+// It must be wrapped by Authenticate, which provides the roles.
+func RequirePermission(permission auth.Permission) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := currentClaims(r)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !auth.HasPermission(claims.Roles, permission) {
+				http.Error(w, fmt.Sprintf("Forbidden: %s %s requires the %s permission, granted to the roles %s",
+					r.Method, r.URL.Path, permission, strings.Join(auth.RolesWith(permission), ", ")), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
 	}
 }
 
+// currentClaims returns the claims of the access token the request was authenticated with.
+func currentClaims(r *http.Request) (auth.Claims, bool) {
+	claims, ok := r.Context().Value("claims").(auth.Claims)
+	return claims, ok
+}
 
-func AuthorsPathParamRouter(w http.ResponseWriter, r *http.Request){
-	if r.Method == http.MethodGet {
-		GetAuthorById(w, r)
-	} else if r.Method == http.MethodPut {
-		UpdateAuthorById(w, r)
-	} else if r.Method == http.MethodDelete {
-		DeleteAuthorById(w, r)
-	} else {
-		http.Error(w, "Invalid request method", http.StatusBadRequest)
+// currentUser returns the user the request was authenticated as, filled from the token claims.
+func currentUser(r *http.Request) (data.User, bool) {
+	claims, ok := currentClaims(r)
+	if !ok {
+		return data.User{}, false
 	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return data.User{}, false
+	}
+	return data.User{ID: id, Email: claims.Email, CustomerID: claims.CustomerID, Roles: claims.Roles}, true
 }
 
+// actingCustomer returns the customer a request acts as, the one linked to its token. Callers
+// granted permission, API keys included, may act for any customer by naming it, others naming
+// another customer than their own get false.
+func actingCustomer(r *http.Request, requested int, permission auth.Permission) (int, bool) {
+	claims, ok := currentClaims(r)
+	if !ok {
+		return 0, false
+	}
+	if requested != 0 && auth.HasPermission(claims.Roles, permission) {
+		return requested, true
+	}
+	if requested != 0 && requested != claims.CustomerID {
+		return 0, false
+	}
+	return claims.CustomerID, true
+}
 
-func Login(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	newUUID, _ := uuid.NewUUID()
-	tokenStore = append(tokenStore, newUUID.String())
-	w.Write([]byte(newUUID.String()))
+// clientIP returns the address of the peer of the connection.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
 
+func bearerToken(r *http.Request) string {
+	return strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
+}
 
-func Authenticate(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("Authorization") == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+// accessLog receives one entry per request, the application logs go to the default logger.
+var accessLog = slog.Default()
 
-		token := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", -1)
-		exists := false 
+// UseAccessLog sets the logger of the request entries.
+func UseAccessLog(logger *slog.Logger) {
+	accessLog = logger
+}
 
-		for _, t := range tokenStore {
-			if t == token {
-				exists = true
-				break
-			}
-		}
+// requestInfo collects what inner middlewares learn about a request for its access log entry.
+type requestInfo struct {
+	subject string
+}
 
-		if !exists {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+// requestID returns the X-Request-ID of the request when it is sane, a new one otherwise.
+func requestID(r *http.Request) string {
+	id := r.Header.Get("X-Request-ID")
+	if id == "" || len(id) > 128 {
+		return uuid.NewString()
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return uuid.NewString()
 		}
+	}
+	return id
+}
 
-		next.ServeHTTP(w, r)
-	})
+// loggerFrom returns the request-scoped logger, carrying the request ID and, once authenticated, the subject.
+func loggerFrom(r *http.Request) *slog.Logger {
+	return logging.FromContext(r.Context())
 }
 
+// RequestLogger gives the request an ID, echoed in the X-Request-ID header, and a logger carrying it.
+// Once the request is served, it writes its access log entry.
 func RequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		file, err := os.OpenFile("requests.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			log.Fatalf("Failed to open log file: %v", err)
-		}
-		defer file.Close()
+		start := time.Now()
+		id := requestID(r)
+		w.Header().Set("X-Request-ID", id)
 
-		logger := log.New(file, "", log.LstdFlags)
+		info := &requestInfo{}
+		logger := slog.Default().With("request_id", id)
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+			logger = logger.With("trace_id", spanContext.TraceID().String())
+		}
+		ctx := logging.WithLogger(r.Context(), logger)
+		ctx = context.WithValue(ctx, "requestInfo", info)
 		lrw := NewLoggingResponseWriter(w)
-		next.ServeHTTP(lrw, r)
-        statusCode := lrw.statusCode
-		logger.Printf("%s - %s %s %s\n", time.Now().Format(time.RFC3339), r.Method, r.URL.Path, http.StatusText(statusCode) )
+		next.ServeHTTP(lrw, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if lrw.statusCode >= 500 {
+			level = slog.LevelError
+		} else if lrw.statusCode >= 400 {
+			level = slog.LevelWarn
+		}
+		accessLog.LogAttrs(ctx, level, "request",
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", routePath(r.Pattern)),
+			slog.Int("status", lrw.statusCode),
+			slog.Int64("bytes", lrw.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", clientIP(r)),
+			slog.String("subject", info.subject),
+			slog.String("user_agent", r.UserAgent()),
+		)
 	})
 }
 
+// requestTimeout bounds the database work of a request.
+var requestTimeout = 5 * time.Second
+
+// UseRequestTimeout sets the deadline ContextGeneration gives the requests.
+func UseRequestTimeout(timeout time.Duration) {
+	requestTimeout = timeout
+}
+
+// ContextGeneration gives the handlers the database template, bound to the request context so
+// queries are cancelled with the request and log with its logger.
 func ContextGeneration(store *data.DBTemplate, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 		defer cancel()
 
-		ctx = context.WithValue(ctx, "memoryStore", store)
+		ctx = context.WithValue(ctx, "memoryStore", store.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func MailerContext(mailer mail.Mailer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "mailer", mailer)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func BlobStoreContext(blobs data.BlobStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "blobStore", blobs)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 
-// loggingResponseWriter is a wrapper around an http.ResponseWriter that keeps track of the status code written to it.
+// loggingResponseWriter is a wrapper around an http.ResponseWriter that keeps track of the status code and the number of bytes written to it.
 type loggingResponseWriter struct {
     http.ResponseWriter
-    statusCode int
+    statusCode  int
+    bytes       int64
+    wroteHeader bool
 }
 
 func NewLoggingResponseWriter(w http.ResponseWriter) *loggingResponseWriter {
-    return &loggingResponseWriter{w, http.StatusOK}
+    return &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
 func (lrw *loggingResponseWriter) WriteHeader(code int) {
-    lrw.statusCode = code
+    if !lrw.wroteHeader {
+        lrw.statusCode = code
+        lrw.wroteHeader = true
+    }
     lrw.ResponseWriter.WriteHeader(code)
+}
+
+func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
+    lrw.wroteHeader = true
+    n, err := lrw.ResponseWriter.Write(b)
+    lrw.bytes += int64(n)
+    return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+    return lrw.ResponseWriter
 }
\ No newline at end of file
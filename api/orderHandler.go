@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/auth"
+	"finalproject/data"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func getOrderRepoFromFactory(w http.ResponseWriter, r *http.Request) (*data.OrderRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+
+	repo, err := data.GetDAO[data.Order]("order", store)
+	if err != nil {
+		http.Error(w, "Failed to retrieve order repository", http.StatusInternalServerError)
+		return nil, err
+	}
+	return repo.(*data.OrderRepository), nil
+}
+
+// canReachOrder tells whether the request may reach an order: the customer who placed it can, and
+// so can staff holding orders:manage or one of the other permissions given. A missing order can't
+// be reached.
+func canReachOrder(r *http.Request, orderID int, permissions ...auth.Permission) (bool, error) {
+	claims, ok := currentClaims(r)
+	if !ok {
+		return false, nil
+	}
+	for _, permission := range append(permissions, auth.PermissionOrdersManage) {
+		if auth.HasPermission(claims.Roles, permission) {
+			return true, nil
+		}
+	}
+
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		return false, errors.New("store not found in context")
+	}
+	customerID, err := data.NewOrderRepository(store).GetCustomerId(orderID)
+	if errors.Is(err, data.ErrOrderNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return claims.CustomerID != 0 && customerID == claims.CustomerID, nil
+}
+
+// authorizeOrder answers the same 404 as for a missing order when the request can't reach the
+// order, so the orders of other customers can't be probed. It returns false once it has answered.
+func authorizeOrder(w http.ResponseWriter, r *http.Request, orderID int, permissions ...auth.Permission) bool {
+	allowed, err := canReachOrder(r, orderID, permissions...)
+	if err != nil {
+		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func GetOrderById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getOrderRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeOrder(w, r, id) {
+		return
+	}
+
+	order, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+type transitionRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+func GetOrderTransitions(w http.ResponseWriter, r *http.Request) {
+	repo, err := getOrderRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeOrder(w, r, id) {
+		return
+	}
+
+	if _, err := repo.GetById(id); err != nil {
+		if errors.Is(err, data.ErrOrderNotFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := repo.GetStatusHistory(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve order status history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// TransitionOrder moves an order along its lifecycle, illegal moves are rejected with 409.
+func TransitionOrder(w http.ResponseWriter, r *http.Request) {
+	repo, err := getOrderRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var request transitionRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if errs := validateTransition(request); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+
+	order, err := repo.Transition(id, request.Status, request.Note)
+	if err != nil {
+		var transitionErr *data.IllegalTransitionError
+		switch {
+		case errors.As(err, &transitionErr):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, data.ErrUnknownOrderStatus):
+			http.Error(w, "Unknown order status "+request.Status, http.StatusBadRequest)
+		case errors.Is(err, data.ErrOrderNotFound):
+			http.Error(w, "Order not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to update order status", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// Checkout places an order for the listed items, or for the session cart when no items are sent.
+func Checkout(w http.ResponseWriter, r *http.Request) {
+	repo, err := getOrderRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	var request data.CheckoutRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	if errs := validateCheckout(request); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+	request.SessionKey = cartSessionKey(r)
+	customerID, ok := actingCustomer(r, request.CustomerID, auth.PermissionOrdersManage)
+	if !ok {
+		http.Error(w, "Placing an order for another customer requires the "+string(auth.PermissionOrdersManage)+" permission", http.StatusForbidden)
+		return
+	}
+	if customerID == 0 {
+		http.Error(w, "Missing customer_id, the account is not linked to a customer", http.StatusBadRequest)
+		return
+	}
+	request.CustomerID = customerID
+
+	order, err := repo.PlaceOrder(request)
+	if err != nil {
+		var stockErr *data.InsufficientStockError
+		var codeErr *data.UnknownPromotionCodeError
+		var shippingErr *data.ShippingUnavailableError
+		switch {
+		case errors.As(err, &stockErr):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.As(err, &codeErr), errors.As(err, &shippingErr):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, data.ErrEmptyCheckout):
+			http.Error(w, "Nothing to check out, the cart is empty", http.StatusBadRequest)
+		case errors.Is(err, data.ErrCustomerNotFound), errors.Is(err, data.ErrBookNotFound):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to place order", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/orders/%d", order.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxJSONBodySize bounds the JSON bodies read by decodeJSON.
+const maxJSONBodySize = 1 << 20
+
+// Problem types, relative to the API.
+const (
+	problemValidation   = "/problems/validation-error"
+	problemMalformed    = "/problems/malformed-body"
+	problemBodyTooLarge = "/problems/body-too-large"
+)
+
+// FieldError is an invalid field of a request body. Field is its path in the body, like
+// "author.id" or "items[0].quantity", and Code a stable identifier of the broken rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p problem) {
+	p.Instance = r.URL.Path
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// writeValidationProblem answers 422 with the invalid fields of the body.
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, errs []FieldError) {
+	writeProblem(w, r, problem{
+		Type:   problemValidation,
+		Title:  "Request body is invalid",
+		Status: http.StatusUnprocessableEntity,
+		Detail: fmt.Sprintf("%d field(s) failed validation", len(errs)),
+		Errors: errs,
+	})
+}
+
+// decodeJSON reads a single JSON value of at most maxJSONBodySize bytes into v, rejecting fields v
+// doesn't have. It answers the problem itself and returns false when the body can't be decoded:
+// 413 when it is too large, 422 for unknown fields and values of the wrong type, 400 otherwise.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBodySize)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil {
+		// Only whitespace may follow the value.
+		if _, next := decoder.Token(); next != io.EOF {
+			err = errors.New("body must hold a single JSON value")
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &sizeErr):
+		writeProblem(w, r, problem{
+			Type:   problemBodyTooLarge,
+			Title:  "Request body is too large",
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("The body must not exceed %d bytes", sizeErr.Limit),
+		})
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "."
+		}
+		writeValidationProblem(w, r, []FieldError{{Field: field, Code: "type", Message: "must be " + jsonKind(typeErr.Type.Kind().String())}})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		writeValidationProblem(w, r, []FieldError{{Field: field, Code: "unknown_field", Message: "is not a known field"}})
+	case errors.Is(err, io.EOF):
+		writeProblem(w, r, problem{Type: problemMalformed, Title: "Request body is malformed", Status: http.StatusBadRequest, Detail: "The body is empty"})
+	case errors.As(err, &syntaxErr):
+		writeProblem(w, r, problem{
+			Type:   problemMalformed,
+			Title:  "Request body is malformed",
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("Invalid JSON at byte %d: %s", syntaxErr.Offset, strings.TrimPrefix(err.Error(), "json: ")),
+		})
+	default:
+		writeProblem(w, r, problem{
+			Type:   problemMalformed,
+			Title:  "Request body is malformed",
+			Status: http.StatusBadRequest,
+			Detail: strings.TrimPrefix(err.Error(), "json: "),
+		})
+	}
+	return false
+}
+
+// jsonKind names the JSON type expected for a Go kind.
+func jsonKind(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "slice", kind == "array":
+		return "an array"
+	}
+	return "an object"
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+func getPromotionRepoFromFactory(w http.ResponseWriter, r *http.Request) (data.IDAO[data.Promotion], error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+
+	repo, err := data.GetDAO[data.Promotion]("promotion", store)
+	if err != nil {
+		http.Error(w, "Failed to retrieve promotion repository", http.StatusInternalServerError)
+		return nil, err
+	}
+	return repo, nil
+}
+
+// validatePromotion checks a promotion rule before it is stored, it returns an empty string when it is valid.
+func validatePromotion(promotion data.Promotion) string {
+	switch {
+	case promotion.Name == "":
+		return "Name is required"
+	case !slices.Contains(data.PromotionKinds, promotion.Kind):
+		return "Kind must be one of percent, fixed or buy_x_get_y"
+	case promotion.Scope == "":
+		return "Scope is required"
+	case !slices.Contains(data.PromotionScopes, promotion.Scope):
+		return "Scope must be one of order, genre, author or book"
+	case promotion.Scope != data.PromotionScopeOrder && promotion.ScopeValue == "":
+		return "Scope value is required for genre, author and book promotions"
+	case promotion.Value < 0 || promotion.MinSpend < 0:
+		return "Value and minimum spend cannot be negative"
+	case promotion.Kind == data.PromotionPercent && promotion.Value > 100:
+		return "A percentage cannot be above 100"
+	case promotion.Kind == data.PromotionBuyXGetY && (promotion.BuyQuantity < 1 || promotion.GetQuantity < 1):
+		return "Buy and get quantities must be positive"
+	case promotion.UsageLimit < 0 || promotion.PerCustomerLimit < 0:
+		return "Usage limits cannot be negative"
+	case promotion.StartsAt != nil && promotion.EndsAt != nil && !promotion.EndsAt.After(*promotion.StartsAt):
+		return "The end date must be after the start date"
+	}
+	if promotion.Scope == data.PromotionScopeAuthor || promotion.Scope == data.PromotionScopeBook {
+		if _, err := strconv.Atoi(promotion.ScopeValue); err != nil {
+			return "Scope value must be an id for author and book promotions"
+		}
+	}
+	return ""
+}
+
+func GetAllPromotions(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPromotionRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	promotions, err := repo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to retrieve promotions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotions)
+}
+
+func CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPromotionRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	promotion := data.Promotion{Scope: data.PromotionScopeOrder, Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if message := validatePromotion(promotion); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	createdPromotion, err := repo.Create(promotion)
+	if err != nil {
+		if errors.Is(err, data.ErrPromotionCodeTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create promotion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdPromotion)
+}
+
+func GetPromotionById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPromotionRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrPromotionNotFound) {
+			http.Error(w, "Promotion not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve promotion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+func UpdatePromotionById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPromotionRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedPromotion data.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&updatedPromotion); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if message := validatePromotion(updatedPromotion); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := repo.Update(id, updatedPromotion)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPromotionNotFound):
+			http.Error(w, "Promotion not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrPromotionCodeTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to update promotion", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+func DeletePromotionById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPromotionRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.Delete(id); err != nil {
+		if errors.Is(err, data.ErrPromotionNotFound) {
+			http.Error(w, "Promotion not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete promotion", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
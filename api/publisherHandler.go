@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"net/http"
+	"strconv"
+)
+
+func getPublisherRepoFromFactory(w http.ResponseWriter, r *http.Request) (data.IDAO[data.Publisher], error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+
+	repo, err := data.GetDAO[data.Publisher]("publisher", store)
+	if err != nil {
+		http.Error(w, "Failed to retrieve publisher repository", http.StatusInternalServerError)
+		return nil, err
+	}
+	return repo, nil
+}
+
+func GetAllPublishers(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPublisherRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	publishers, err := repo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to retrieve publishers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publishers)
+}
+
+func CreatePublisher(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPublisherRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	var publisher data.Publisher
+	if !decodeJSON(w, r, &publisher) {
+		return
+	}
+	if errs := validatePublisher(publisher); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+
+	createdPublisher, err := repo.Create(publisher)
+	if err != nil {
+		if errors.Is(err, data.ErrPublisherNameTaken) {
+			http.Error(w, "A publisher with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create publisher", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdPublisher)
+}
+
+func GetPublisherById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPublisherRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid publisher ID", http.StatusBadRequest)
+		return
+	}
+
+	publisher, err := repo.GetById(id)
+	if err != nil {
+		http.Error(w, "Publisher not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publisher)
+}
+
+func UpdatePublisherById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPublisherRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid publisher ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedPublisher data.Publisher
+	if !decodeJSON(w, r, &updatedPublisher) {
+		return
+	}
+	if errs := validatePublisher(updatedPublisher); len(errs) > 0 {
+		writeValidationProblem(w, r, errs)
+		return
+	}
+
+	publisher, err := repo.Update(id, updatedPublisher)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPublisherNotFound):
+			http.Error(w, "Publisher not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrPublisherNameTaken):
+			http.Error(w, "A publisher with this name already exists", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to update publisher", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publisher)
+}
+
+func DeletePublisherById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getPublisherRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid publisher ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.Delete(id); err != nil {
+		if errors.Is(err, data.ErrPublisherNotFound) {
+			http.Error(w, "Publisher not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete publisher", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"finalproject/auth"
+	"finalproject/ratelimit"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitPolicy gives the limit of a group of routes, by role. Anonymous applies to requests
+// without credentials, Default to the others whose roles have no limit of their own.
+// With several roles, the highest limit applies.
+type RateLimitPolicy struct {
+	Name      string
+	Anonymous ratelimit.Limit
+	Default   ratelimit.Limit
+	Roles     map[string]ratelimit.Limit
+}
+
+var (
+	// credentialsRateLimit slows down password guessing and account enumeration.
+	credentialsRateLimit = RateLimitPolicy{
+		Name:      "credentials",
+		Anonymous: ratelimit.Limit{Requests: 10, Per: time.Minute},
+		Default:   ratelimit.Limit{Requests: 10, Per: time.Minute},
+	}
+	// The catalog routes all need credentials, so the policy has no anonymous limit.
+	catalogRateLimit = RateLimitPolicy{
+		Name:    "catalog",
+		Default: ratelimit.Limit{Requests: 120, Per: time.Minute},
+		Roles: map[string]ratelimit.Limit{
+			auth.RoleAdmin:         {Requests: 1200, Per: time.Minute},
+			auth.RoleCatalogEditor: {Requests: 600, Per: time.Minute},
+		},
+	}
+	checkoutRateLimit = RateLimitPolicy{
+		Name:    "checkout",
+		Default: ratelimit.Limit{Requests: 10, Per: time.Minute},
+		Roles: map[string]ratelimit.Limit{
+			auth.RoleAdmin: {Requests: 120, Per: time.Minute},
+		},
+	}
+	defaultRateLimit = RateLimitPolicy{
+		Name:      "default",
+		Anonymous: ratelimit.Limit{Requests: 60, Per: time.Minute},
+		Default:   ratelimit.Limit{Requests: 300, Per: time.Minute},
+		Roles: map[string]ratelimit.Limit{
+			auth.RoleAdmin:           {Requests: 1200, Per: time.Minute},
+			auth.RoleCustomerSupport: {Requests: 600, Per: time.Minute},
+		},
+	}
+)
+
+// clientIPRateLimit caps the requests of an IP address before their credentials are checked, so
+// requests with guessed tokens or API keys are throttled too. It is above the highest limit of a
+// role, so it only bites clients that aren't let in.
+var clientIPRateLimit = ratelimit.Limit{Requests: 1200, Per: time.Minute}
+
+// routeRateLimits gives the policy of each route pattern, the others use defaultRateLimit.
+// Routes sharing a policy share its buckets.
+var routeRateLimits = map[string]RateLimitPolicy{
+	"/login":                  credentialsRateLimit,
+	"/register":               credentialsRateLimit,
+	"/password-reset":         credentialsRateLimit,
+	"/password-reset/confirm": credentialsRateLimit,
+	"/token/refresh":          credentialsRateLimit,
+	"/books":                  catalogRateLimit,
+	"/books/{id}":             catalogRateLimit,
+	"/books/{id}/cover":       catalogRateLimit,
+	"/books/{id}/reviews":     catalogRateLimit,
+	"/authors":                catalogRateLimit,
+	"/authors/{id}":           catalogRateLimit,
+	"/publishers":             catalogRateLimit,
+	"/publishers/{id}":        catalogRateLimit,
+	"/checkout":               checkoutRateLimit,
+}
+
+// rateLimits keeps the buckets, in memory unless a shared store is set.
+var rateLimits ratelimit.Store = ratelimit.NewMemoryStore()
+
+// UseRateLimitStore sets the store of the rate limit buckets.
+func UseRateLimitStore(store ratelimit.Store) {
+	rateLimits = store
+}
+
+// limitFor returns the limit of a policy for the roles of a request.
+func (policy RateLimitPolicy) limitFor(roles []string, authenticated bool) ratelimit.Limit {
+	if !authenticated && policy.Anonymous.Requests > 0 {
+		return policy.Anonymous
+	}
+	limit, found := ratelimit.Limit{}, false
+	for _, role := range roles {
+		if roleLimit, ok := policy.Roles[role]; ok && (!found || roleLimit.Rate() > limit.Rate()) {
+			limit, found = roleLimit, true
+		}
+	}
+	if !found {
+		return policy.Default
+	}
+	return limit
+}
+
+// takeToken takes a token from the bucket of key and sets the rate limit headers. Over the limit it
+// answers 429 and returns false.
+func takeToken(w http.ResponseWriter, r *http.Request, key string, limit ratelimit.Limit) bool {
+	_, span := tracer.Start(r.Context(), "rate limit")
+	result, err := rateLimits.Take(key, limit, time.Now().UTC())
+	span.End()
+	if err != nil {
+		// The store failing must not take the API down with it.
+		loggerFrom(r).Error("rate limit store failed, request let through", "error", err)
+		return true
+	}
+
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Per.Seconds())))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		http.Error(w, "Too many requests, retry later", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// RateLimitIP limits the requests of each IP address to clientIPRateLimit. It goes before
+// Authenticate, so requests whose credentials are refused are counted.
+func RateLimitIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if takeToken(w, r, "client-ip:"+clientIP(r), clientIPRateLimit) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// RateLimit limits the requests of each client, identified by its session, its API key or, without
+// credentials, its IP address. It must be inside Authenticate on authenticated routes, to see the client.
+func RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := routeRateLimits[routePath(r.Pattern)]
+		if !ok {
+			policy = defaultRateLimit
+		}
+
+		claims, authenticated := currentClaims(r)
+		client := "ip:" + clientIP(r)
+		if authenticated {
+			// The session of API keys names the key.
+			client = "session:" + claims.SessionID
+		}
+		if takeToken(w, r, policy.Name+":"+client, policy.limitFor(claims.Roles, authenticated)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/auth"
+	"finalproject/data"
+	"net/http"
+	"strconv"
+)
+
+type returnDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+func getReturnRepoFromContext(w http.ResponseWriter, r *http.Request) (*data.ReturnRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+	return data.NewReturnRepository(store), nil
+}
+
+func returnError(w http.ResponseWriter, err error) {
+	var notAllowedErr *data.ReturnNotAllowedError
+	var quantityErr *data.ReturnQuantityError
+	switch {
+	case errors.As(err, &notAllowedErr), errors.As(err, &quantityErr), errors.Is(err, data.ErrReturnAlreadyDecided):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, data.ErrReturnNotFound):
+		http.Error(w, "Return request not found", http.StatusNotFound)
+	case errors.Is(err, data.ErrOrderItemNotFound):
+		http.Error(w, "Order item not found", http.StatusNotFound)
+	case errors.Is(err, data.ErrOrderNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to process return request", http.StatusInternalServerError)
+	}
+}
+
+func CreateOrderReturn(w http.ResponseWriter, r *http.Request) {
+	repo, err := getReturnRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeOrder(w, r, orderID, auth.PermissionReturnsManage) {
+		return
+	}
+
+	var request data.ReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if request.Quantity < 1 {
+		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+		return
+	}
+	request.OrderID = orderID
+
+	created, err := repo.Create(request)
+	if err != nil {
+		returnError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func GetOrderReturns(w http.ResponseWriter, r *http.Request) {
+	repo, err := getReturnRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeOrder(w, r, orderID, auth.PermissionReturnsManage) {
+		return
+	}
+
+	returns, err := repo.GetByOrderId(orderID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve return requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(returns)
+}
+
+func GetAllReturns(w http.ResponseWriter, r *http.Request) {
+	repo, err := getReturnRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	returns, err := repo.GetAll(r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Failed to retrieve return requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(returns)
+}
+
+func GetReturnById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getReturnRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid return request ID", http.StatusBadRequest)
+		return
+	}
+
+	request, err := repo.GetById(id)
+	if err != nil {
+		returnError(w, err)
+		return
+	}
+	allowed, err := canReachOrder(r, request.OrderID, auth.PermissionReturnsManage)
+	if err != nil {
+		returnError(w, err)
+		return
+	}
+	if !allowed {
+		returnError(w, data.ErrReturnNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+func decideReturn(w http.ResponseWriter, r *http.Request, approve bool) {
+	repo, err := getReturnRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid return request ID", http.StatusBadRequest)
+		return
+	}
+
+	var decision returnDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var request data.ReturnRequest
+	if approve {
+		request, err = repo.Approve(id, decision.Note)
+	} else {
+		request, err = repo.Reject(id, decision.Note)
+	}
+	if err != nil {
+		returnError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// ApproveReturn restocks the returned units and records the refund.
+func ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	decideReturn(w, r, true)
+}
+
+func RejectReturn(w http.ResponseWriter, r *http.Request) {
+	decideReturn(w, r, false)
+}
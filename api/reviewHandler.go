@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/auth"
+	"finalproject/data"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultReviewPageSize = 20
+	maxReviewPageSize     = 100
+)
+
+func getReviewRepoFromFactory(w http.ResponseWriter, r *http.Request) (*data.ReviewRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+
+	repo, err := data.GetDAO[data.Review]("review", store)
+	if err != nil {
+		http.Error(w, "Failed to retrieve review repository", http.StatusInternalServerError)
+		return nil, err
+	}
+	return repo.(*data.ReviewRepository), nil
+}
+
+// queryInt reads a positive integer query parameter, falling back to def when it is absent.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.New("invalid " + name)
+	}
+	return n, nil
+}
+
+func GetBookReviews(w http.ResponseWriter, r *http.Request) {
+	bookRepo, err := getBookRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+	repo, err := getReviewRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := queryInt(r, "page", 1)
+	if err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	pageSize, err := queryInt(r, "page_size", defaultReviewPageSize)
+	if err != nil || pageSize > maxReviewPageSize {
+		http.Error(w, "Invalid page_size, must be between 1 and 100", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := bookRepo.GetById(id); err != nil {
+		http.Error(w, "Book not found", http.StatusNotFound)
+		return
+	}
+
+	reviews, err := repo.GetByBookId(id, page, pageSize)
+	if err != nil {
+		http.Error(w, "Failed to retrieve reviews", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+func CreateBookReview(w http.ResponseWriter, r *http.Request) {
+	repo, err := getReviewRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	var review data.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if review.Rating < 1 || review.Rating > 5 {
+		http.Error(w, "Rating must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+	customerID, ok := actingCustomer(r, review.CustomerID, auth.PermissionReviewsManage)
+	if !ok {
+		http.Error(w, "Reviewing as another customer requires the "+string(auth.PermissionReviewsManage)+" permission", http.StatusForbidden)
+		return
+	}
+	if customerID == 0 {
+		http.Error(w, "Missing customer_id, the account is not linked to a customer", http.StatusBadRequest)
+		return
+	}
+	review.CustomerID = customerID
+	review.BookID = id
+
+	createdReview, err := repo.Create(review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrReviewNotVerified):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, data.ErrReviewExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, data.ErrBookNotFound):
+			http.Error(w, "Book not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to create review", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdReview)
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/auth"
+	"finalproject/data"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+type roleAssignment struct {
+	Roles []string `json:"roles"`
+}
+
+// GetRoles lists the roles and the permissions each one grants.
+func GetRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.RolePermissions)
+}
+
+func GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roleAssignment{Roles: user.Roles})
+}
+
+// SetUserRoles replaces the roles of a user. Roles are read from the access tokens, so when a role
+// is taken away the sessions of the user are revoked for the change to apply at once.
+func SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+	admin, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var request roleAssignment
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	roles := []string{}
+	for _, role := range request.Roles {
+		if !auth.IsKnownRole(role) {
+			http.Error(w, "Unknown role "+role, http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	if id == admin.ID && !auth.HasPermission(roles, auth.PermissionUsersManage) {
+		http.Error(w, "You can't take away your own permission to manage users", http.StatusConflict)
+		return
+	}
+
+	previous, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update roles", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := repo.SetRoles(id, roles)
+	if err != nil {
+		if errors.Is(err, data.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update roles", http.StatusInternalServerError)
+		return
+	}
+
+	for _, role := range previous.Roles {
+		if !slices.Contains(roles, role) {
+			claims, _ := currentClaims(r)
+			keep := ""
+			if id == admin.ID {
+				keep = claims.SessionID
+			}
+			if _, err := revokeUserSessions(refreshTokens, id, keep); err != nil {
+				http.Error(w, "Roles updated, but failed to revoke sessions", http.StatusInternalServerError)
+				return
+			}
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roleAssignment{Roles: user.Roles})
+}
+
+type customerLink struct {
+	CustomerID int `json:"customer_id"`
+}
+
+// SetUserCustomer links a user to the customer it buys as, or unlinks it with 0. Registration links
+// no customer, so orders, invoices and returns are only reachable once an administrator has checked
+// who the user is. The customer is read from the access tokens, so sessions are revoked when an
+// existing link changes.
+func SetUserCustomer(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var request customerLink
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.CustomerID < 0 {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	previous, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to link customer", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := repo.SetCustomer(id, request.CustomerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrCustomerNotFound):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, data.ErrCustomerAlreadyTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to link customer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if previous.CustomerID != 0 && previous.CustomerID != user.CustomerID {
+		if _, err := revokeUserSessions(refreshTokens, id, ""); err != nil {
+			http.Error(w, "Customer linked, but failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customerLink{CustomerID: user.CustomerID})
+}
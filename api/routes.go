@@ -0,0 +1,171 @@
+package api
+
+import (
+	"finalproject/auth"
+	"finalproject/data"
+	"finalproject/mail"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Middleware wraps a handler.
+type Middleware func(http.Handler) http.Handler
+
+// Route is an endpoint: a method and a path pattern, its handler and the middlewares it runs
+// through, the first one outermost.
+type Route struct {
+	Method     string
+	Path       string
+	Handler    http.HandlerFunc
+	Middleware []Middleware
+}
+
+// Pattern is the ServeMux pattern of the route, like "GET /books/{id}".
+func (route Route) Pattern() string {
+	return route.Method + " " + route.Path
+}
+
+// chain copies middlewares into a new list, so route chains built from a common one don't share it.
+func chain(middlewares ...Middleware) []Middleware {
+	return slices.Clone(middlewares)
+}
+
+// Routes is the route table of the API.
+func Routes(template *data.DBTemplate, mailer mail.Mailer, blobs data.BlobStore) []Route {
+	withDB := func(next http.Handler) http.Handler { return ContextGeneration(template, next) }
+	withMailer := func(next http.Handler) http.Handler { return MailerContext(mailer, next) }
+	withBlobs := func(next http.Handler) http.Handler { return BlobStoreContext(blobs, next) }
+
+	// RateLimitIP comes before Authenticate so refused credentials are throttled, RateLimit after it
+	// so the limit of the client applies.
+	public := chain(RequestLogger, RateLimitIP, withDB, RateLimit)
+	signedIn := chain(RequestLogger, RateLimitIP, Authenticate, withDB, RateLimit)
+	permitted := func(permission auth.Permission) []Middleware {
+		return chain(RequestLogger, RateLimitIP, Authenticate, RequirePermission(permission), withDB, RateLimit)
+	}
+
+	return []Route{
+		{http.MethodPost, "/login", Login, public},
+		{http.MethodPost, "/token/refresh", RefreshToken, public},
+		{http.MethodGet, "/.well-known/jwks.json", GetJWKS, chain(RequestLogger, RateLimitIP, RateLimit)},
+		{http.MethodPost, "/register", Register, public},
+		{http.MethodPost, "/password-reset", RequestPasswordReset, append(chain(public...), withMailer)},
+		{http.MethodPost, "/password-reset/confirm", ConfirmPasswordReset, public},
+
+		{http.MethodGet, "/users/me", GetCurrentUser, signedIn},
+		{http.MethodPut, "/users/me/password", ChangePassword, signedIn},
+		{http.MethodPost, "/logout", Logout, signedIn},
+		{http.MethodGet, "/sessions", GetSessions, signedIn},
+		{http.MethodDelete, "/sessions/{id}", RevokeSession, signedIn},
+		{http.MethodDelete, "/users/{id}/sessions", RevokeUserSessions, permitted(auth.PermissionSessionsManage)},
+		{http.MethodGet, "/roles", GetRoles, permitted(auth.PermissionUsersManage)},
+		{http.MethodGet, "/users/{id}/roles", GetUserRoles, permitted(auth.PermissionUsersManage)},
+		{http.MethodPut, "/users/{id}/roles", SetUserRoles, permitted(auth.PermissionUsersManage)},
+		{http.MethodPut, "/users/{id}/customer", SetUserCustomer, permitted(auth.PermissionUsersManage)},
+		{http.MethodGet, "/api-keys", GetAllAPIKeys, permitted(auth.PermissionAPIKeysManage)},
+		{http.MethodPost, "/api-keys", CreateAPIKey, permitted(auth.PermissionAPIKeysManage)},
+		{http.MethodGet, "/api-keys/{id}", GetAPIKeyById, permitted(auth.PermissionAPIKeysManage)},
+		{http.MethodDelete, "/api-keys/{id}", RevokeAPIKey, permitted(auth.PermissionAPIKeysManage)},
+		{http.MethodPost, "/api-keys/{id}/rotate", RotateAPIKey, permitted(auth.PermissionAPIKeysManage)},
+
+		{http.MethodPost, "/customers", CreateCustomer, permitted(auth.PermissionCustomersManage)},
+		{http.MethodGet, "/customers/{id}", GetCustomerById, permitted(auth.PermissionCustomersManage)},
+		{http.MethodPut, "/customers/{id}", UpdateCustomerById, permitted(auth.PermissionCustomersManage)},
+
+		{http.MethodGet, "/books", GetAllBooks, signedIn},
+		{http.MethodPost, "/books", CreateBook, permitted(auth.PermissionCatalogWrite)},
+		{http.MethodGet, "/books/{id}", GetBookById, signedIn},
+		{http.MethodPut, "/books/{id}", UpdateBookById, permitted(auth.PermissionCatalogWrite)},
+		{http.MethodDelete, "/books/{id}", DeleteBookById, append(permitted(auth.PermissionCatalogWrite), withBlobs)},
+		{http.MethodGet, "/books/{id}/cover", GetBookCover, append(chain(signedIn...), withBlobs)},
+		{http.MethodPut, "/books/{id}/cover", UploadBookCover, append(permitted(auth.PermissionCatalogWrite), withBlobs)},
+		{http.MethodGet, "/books/{id}/reviews", GetBookReviews, signedIn},
+		{http.MethodPost, "/books/{id}/reviews", CreateBookReview, signedIn},
+		{http.MethodGet, "/authors", GetAllAuthors, signedIn},
+		{http.MethodPost, "/authors", CreateAuthor, permitted(auth.PermissionCatalogWrite)},
+		{http.MethodGet, "/authors/{id}", GetAuthorById, signedIn},
+		{http.MethodPut, "/authors/{id}", UpdateAuthorById, permitted(auth.PermissionCatalogWrite)},
+		{http.MethodDelete, "/authors/{id}", DeleteAuthorById, permitted(auth.PermissionCatalogWrite)},
+		{http.MethodGet, "/publishers", GetAllPublishers, signedIn},
+		{http.MethodPost, "/publishers", CreatePublisher, permitted(auth.PermissionCatalogWrite)},
+		{http.MethodGet, "/publishers/{id}", GetPublisherById, signedIn},
+		{http.MethodPut, "/publishers/{id}", UpdatePublisherById, permitted(auth.PermissionCatalogWrite)},
+		{http.MethodDelete, "/publishers/{id}", DeletePublisherById, permitted(auth.PermissionCatalogWrite)},
+
+		{http.MethodGet, "/cart", GetCart, signedIn},
+		{http.MethodDelete, "/cart", ClearCart, signedIn},
+		{http.MethodPost, "/cart/items", AddCartItem, signedIn},
+		{http.MethodPut, "/cart/items/{bookId}", UpdateCartItem, signedIn},
+		{http.MethodDelete, "/cart/items/{bookId}", RemoveCartItem, signedIn},
+		{http.MethodPost, "/checkout", Checkout, signedIn},
+
+		{http.MethodGet, "/orders/{id}", GetOrderById, signedIn},
+		{http.MethodGet, "/orders/{id}/transitions", GetOrderTransitions, signedIn},
+		{http.MethodPost, "/orders/{id}/transitions", TransitionOrder, permitted(auth.PermissionOrdersManage)},
+		{http.MethodGet, "/orders/{id}/returns", GetOrderReturns, signedIn},
+		{http.MethodPost, "/orders/{id}/returns", CreateOrderReturn, signedIn},
+		{http.MethodGet, "/orders/{id}/invoice", GetOrderInvoice, signedIn},
+		{http.MethodGet, "/orders/{id}/shipments", GetOrderShipments, signedIn},
+		{http.MethodPost, "/orders/{id}/shipments", CreateOrderShipment, permitted(auth.PermissionOrdersManage)},
+		{http.MethodGet, "/shipments/{id}", GetShipmentById, signedIn},
+		{http.MethodPost, "/shipments/{id}/events", AddShipmentEvent, permitted(auth.PermissionOrdersManage)},
+		{http.MethodGet, "/returns", GetAllReturns, permitted(auth.PermissionReturnsManage)},
+		{http.MethodGet, "/returns/{id}", GetReturnById, signedIn},
+		{http.MethodPost, "/returns/{id}/approve", ApproveReturn, permitted(auth.PermissionReturnsManage)},
+		{http.MethodPost, "/returns/{id}/reject", RejectReturn, permitted(auth.PermissionReturnsManage)},
+
+		// Promotions hold the unpublished coupon codes, only pricing staff read them.
+		{http.MethodGet, "/promotions", GetAllPromotions, permitted(auth.PermissionPricingWrite)},
+		{http.MethodPost, "/promotions", CreatePromotion, permitted(auth.PermissionPricingWrite)},
+		{http.MethodGet, "/promotions/{id}", GetPromotionById, permitted(auth.PermissionPricingWrite)},
+		{http.MethodPut, "/promotions/{id}", UpdatePromotionById, permitted(auth.PermissionPricingWrite)},
+		{http.MethodDelete, "/promotions/{id}", DeletePromotionById, permitted(auth.PermissionPricingWrite)},
+		{http.MethodGet, "/tax-rules", GetAllTaxRules, signedIn},
+		{http.MethodPost, "/tax-rules", CreateTaxRule, permitted(auth.PermissionPricingWrite)},
+		{http.MethodGet, "/tax-rules/{id}", GetTaxRuleById, signedIn},
+		{http.MethodPut, "/tax-rules/{id}", UpdateTaxRuleById, permitted(auth.PermissionPricingWrite)},
+		{http.MethodDelete, "/tax-rules/{id}", DeleteTaxRuleById, permitted(auth.PermissionPricingWrite)},
+		{http.MethodGet, "/shipping-zones", GetAllShippingZones, signedIn},
+		{http.MethodPost, "/shipping-zones", CreateShippingZone, permitted(auth.PermissionPricingWrite)},
+		{http.MethodGet, "/shipping-zones/{id}", GetShippingZoneById, signedIn},
+		{http.MethodPut, "/shipping-zones/{id}", UpdateShippingZoneById, permitted(auth.PermissionPricingWrite)},
+		{http.MethodDelete, "/shipping-zones/{id}", DeleteShippingZoneById, permitted(auth.PermissionPricingWrite)},
+
+		// Probes of the load balancer or orchestrator, neither logged nor rate limited.
+		{http.MethodGet, "/healthz", GetHealth, nil},
+		{http.MethodGet, "/readyz", GetReadiness, chain(withDB)},
+	}
+}
+
+// RegisterRoutes adds the routes to mux. The mux serves HEAD with the GET handler of a path and answers
+// other methods with 405 and an Allow header; OPTIONS is answered here, with the same Allow header.
+func RegisterRoutes(mux *http.ServeMux, routes []Route) {
+	var paths []string
+	allowed := map[string][]string{}
+	for _, route := range routes {
+		var handler http.Handler = route.Handler
+		for i := len(route.Middleware) - 1; i >= 0; i-- {
+			handler = route.Middleware[i](handler)
+		}
+		mux.Handle(route.Pattern(), handler)
+
+		if _, ok := allowed[route.Path]; !ok {
+			paths = append(paths, route.Path)
+		}
+		allowed[route.Path] = append(allowed[route.Path], route.Method)
+		if route.Method == http.MethodGet {
+			allowed[route.Path] = append(allowed[route.Path], http.MethodHead)
+		}
+	}
+
+	for _, path := range paths {
+		methods := append(allowed[path], http.MethodOptions)
+		slices.Sort(methods)
+		allow := strings.Join(methods, ", ")
+		mux.Handle(http.MethodOptions+" "+path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/auth"
+	"finalproject/data"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// revokeUserSessions revokes the sessions of a user and their refresh tokens, except the session keepID.
+func revokeUserSessions(refreshTokens *data.RefreshTokenRepository, userID int, keepID string) (int, error) {
+	revoked, err := sessions.RevokeUser(userID, keepID, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return revoked, refreshTokens.RevokeUser(userID, keepID)
+}
+
+// Logout ends the session of the access token, its refresh token can no longer be used.
+func Logout(w http.ResponseWriter, r *http.Request) {
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+	claims, ok := currentClaims(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := sessions.Revoke(claims.SessionID, time.Now().UTC()); err != nil && !errors.Is(err, data.ErrSessionNotFound) {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+	if err := refreshTokens.RevokeFamily(claims.SessionID); err != nil {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSessions lists the active sessions of the current user. With the sessions:manage permission,
+// those of another user may be listed with ?user_id=.
+func GetSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	claims, _ := currentClaims(r)
+
+	userID := user.ID
+	if param := r.URL.Query().Get("user_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if id != user.ID && !auth.HasPermission(user.Roles, auth.PermissionSessionsManage) {
+			http.Error(w, "Forbidden: listing the sessions of other users requires the sessions:manage permission", http.StatusForbidden)
+			return
+		}
+		userID = id
+	}
+
+	active, err := sessions.ListActive(userID, time.Now().UTC())
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+	for i := range active {
+		active[i].Current = active[i].ID == claims.SessionID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(active)
+}
+
+// RevokeSession ends one session of the current user. Any session may be ended with the sessions:manage permission.
+func RevokeSession(w http.ResponseWriter, r *http.Request) {
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	session, err := sessions.Get(id)
+	if err != nil {
+		if errors.Is(err, data.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if session.UserID != user.ID && !auth.HasPermission(user.Roles, auth.PermissionSessionsManage) {
+		// Sessions of other users are reported as missing, so their ids can't be probed.
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := sessions.Revoke(id, time.Now().UTC()); err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if err := refreshTokens.RevokeFamily(id); err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessions ends every session of a user.
+func RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := revokeUserSessions(refreshTokens, userID, "")
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func getShipmentRepoFromContext(w http.ResponseWriter, r *http.Request) (*data.ShipmentRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+	return data.NewShipmentRepository(store), nil
+}
+
+func shipmentError(w http.ResponseWriter, err error) {
+	var notAllowedErr *data.ShipmentNotAllowedError
+	var quantityErr *data.ShipmentQuantityError
+	var transitionErr *data.IllegalTransitionError
+	switch {
+	case errors.As(err, &notAllowedErr), errors.As(err, &quantityErr), errors.As(err, &transitionErr),
+		errors.Is(err, data.ErrNothingToShip), errors.Is(err, data.ErrShipmentDelivered):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, data.ErrShipmentTrackingNeeded), errors.Is(err, data.ErrUnknownShipmentStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, data.ErrShipmentNotFound):
+		http.Error(w, "Shipment not found", http.StatusNotFound)
+	case errors.Is(err, data.ErrOrderNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+	case errors.Is(err, data.ErrOrderItemNotFound):
+		http.Error(w, "Order item not found", http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to process shipment", http.StatusInternalServerError)
+	}
+}
+
+func GetOrderShipments(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShipmentRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeOrder(w, r, orderID) {
+		return
+	}
+
+	shipments, err := repo.GetByOrderId(orderID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve shipments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipments)
+}
+
+// CreateOrderShipment ships the listed items of an order, or all of its unshipped items when none are listed.
+func CreateOrderShipment(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShipmentRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var shipment data.Shipment
+	if err := json.NewDecoder(r.Body).Decode(&shipment); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	for _, item := range shipment.Items {
+		if item.Quantity < 1 {
+			http.Error(w, "Quantities must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+	shipment.OrderID = orderID
+
+	created, err := repo.Create(shipment)
+	if err != nil {
+		shipmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/shipments/%d", created.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func GetShipmentById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShipmentRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shipment ID", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := repo.GetById(id)
+	if err != nil {
+		shipmentError(w, err)
+		return
+	}
+	allowed, err := canReachOrder(r, shipment.OrderID)
+	if err != nil {
+		shipmentError(w, err)
+		return
+	}
+	if !allowed {
+		shipmentError(w, data.ErrShipmentNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
+
+// AddShipmentEvent records a carrier tracking update for a shipment.
+func AddShipmentEvent(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShipmentRepoFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shipment ID", http.StatusBadRequest)
+		return
+	}
+
+	var event data.ShipmentEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := repo.AddEvent(id, event)
+	if err != nil {
+		shipmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
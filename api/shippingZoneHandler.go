@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func getShippingZoneRepoFromFactory(w http.ResponseWriter, r *http.Request) (data.IDAO[data.ShippingZone], error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+
+	repo, err := data.GetDAO[data.ShippingZone]("shippingZone", store)
+	if err != nil {
+		http.Error(w, "Failed to retrieve shipping zone repository", http.StatusInternalServerError)
+		return nil, err
+	}
+	return repo, nil
+}
+
+// decodeShippingZone reads a shipping zone from the request body, it writes the error response when the zone is invalid.
+func decodeShippingZone(w http.ResponseWriter, r *http.Request) (data.ShippingZone, bool) {
+	var zone data.ShippingZone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return data.ShippingZone{}, false
+	}
+	for i, country := range zone.Countries {
+		zone.Countries[i] = strings.TrimSpace(country)
+	}
+
+	switch {
+	case zone.Name == "" || len(zone.Countries) == 0 || slices.Contains(zone.Countries, ""):
+		http.Error(w, "Name and countries are required", http.StatusBadRequest)
+		return data.ShippingZone{}, false
+	case zone.RateBasis != data.ShippingByWeight && zone.RateBasis != data.ShippingByItems:
+		http.Error(w, "Rate basis must be weight or items", http.StatusBadRequest)
+		return data.ShippingZone{}, false
+	case zone.BaseRate < 0 || zone.UnitRate < 0 || zone.FreeShippingThreshold < 0:
+		http.Error(w, "Rates and threshold cannot be negative", http.StatusBadRequest)
+		return data.ShippingZone{}, false
+	}
+	return zone, true
+}
+
+func GetAllShippingZones(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShippingZoneRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	zones, err := repo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to retrieve shipping zones", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
+
+func CreateShippingZone(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShippingZoneRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	zone, ok := decodeShippingZone(w, r)
+	if !ok {
+		return
+	}
+
+	createdZone, err := repo.Create(zone)
+	if err != nil {
+		if errors.Is(err, data.ErrShippingZoneExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create shipping zone", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdZone)
+}
+
+func GetShippingZoneById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShippingZoneRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrShippingZoneNotFound) {
+			http.Error(w, "Shipping zone not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve shipping zone", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+func UpdateShippingZoneById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShippingZoneRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+
+	updatedZone, ok := decodeShippingZone(w, r)
+	if !ok {
+		return
+	}
+
+	zone, err := repo.Update(id, updatedZone)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrShippingZoneNotFound):
+			http.Error(w, "Shipping zone not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrShippingZoneExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to update shipping zone", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+func DeleteShippingZoneById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getShippingZoneRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.Delete(id); err != nil {
+		if errors.Is(err, data.ErrShippingZoneNotFound) {
+			http.Error(w, "Shipping zone not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete shipping zone", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
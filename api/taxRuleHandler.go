@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/data"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func getTaxRuleRepoFromFactory(w http.ResponseWriter, r *http.Request) (data.IDAO[data.TaxRule], error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+
+	repo, err := data.GetDAO[data.TaxRule]("taxRule", store)
+	if err != nil {
+		http.Error(w, "Failed to retrieve tax rule repository", http.StatusInternalServerError)
+		return nil, err
+	}
+	return repo, nil
+}
+
+// decodeTaxRule reads a tax rule from the request body, it writes the error response when the rule is invalid.
+func decodeTaxRule(w http.ResponseWriter, r *http.Request) (data.TaxRule, bool) {
+	var rule data.TaxRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return data.TaxRule{}, false
+	}
+	rule.Country = strings.TrimSpace(rule.Country)
+	rule.State = strings.TrimSpace(rule.State)
+	rule.Category = strings.TrimSpace(rule.Category)
+
+	switch {
+	case rule.Name == "" || rule.Country == "":
+		http.Error(w, "Name and country are required", http.StatusBadRequest)
+		return data.TaxRule{}, false
+	case rule.Rate < 0 || rule.Rate > 100:
+		http.Error(w, "Rate must be a percentage between 0 and 100", http.StatusBadRequest)
+		return data.TaxRule{}, false
+	}
+	return rule, true
+}
+
+func GetAllTaxRules(w http.ResponseWriter, r *http.Request) {
+	repo, err := getTaxRuleRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	rules, err := repo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to retrieve tax rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+func CreateTaxRule(w http.ResponseWriter, r *http.Request) {
+	repo, err := getTaxRuleRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	rule, ok := decodeTaxRule(w, r)
+	if !ok {
+		return
+	}
+
+	createdRule, err := repo.Create(rule)
+	if err != nil {
+		if errors.Is(err, data.ErrTaxRuleExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create tax rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdRule)
+}
+
+func GetTaxRuleById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getTaxRuleRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid tax rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := repo.GetById(id)
+	if err != nil {
+		if errors.Is(err, data.ErrTaxRuleNotFound) {
+			http.Error(w, "Tax rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve tax rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func UpdateTaxRuleById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getTaxRuleRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid tax rule ID", http.StatusBadRequest)
+		return
+	}
+
+	updatedRule, ok := decodeTaxRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := repo.Update(id, updatedRule)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrTaxRuleNotFound):
+			http.Error(w, "Tax rule not found", http.StatusNotFound)
+		case errors.Is(err, data.ErrTaxRuleExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to update tax rule", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func DeleteTaxRuleById(w http.ResponseWriter, r *http.Request) {
+	repo, err := getTaxRuleRepoFromFactory(w, r)
+	if err != nil {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid tax rule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.Delete(id); err != nil {
+		if errors.Is(err, data.ErrTaxRuleNotFound) {
+			http.Error(w, "Tax rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete tax rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"finalproject/tracing"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("api")
+
+// Tracing opens the server span of each request, continuing the trace of its traceparent header.
+// Like Metrics it wraps the mux itself, and names the span after the route once the mux matched it.
+func Tracing(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(clientIP(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		lrw := NewLoggingResponseWriter(w)
+		r = r.WithContext(ctx)
+		mux.ServeHTTP(lrw, r)
+
+		if route := routePath(r.Pattern); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(lrw.statusCode))
+		if lrw.statusCode >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(lrw.statusCode))
+		}
+	})
+}
+
+// annotateSpan adds attributes to the server span of the request.
+func annotateSpan(r *http.Request, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(r.Context()).SetAttributes(attrs...)
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"finalproject/auth"
+	"finalproject/data"
+	"finalproject/mail"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type registrationRequest struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Scope    string `json:"scope"`
+}
+
+type tokenResponse struct {
+	AccessToken  string     `json:"access_token"`
+	TokenType    string     `json:"token_type"`
+	ExpiresIn    int        `json:"expires_in"`
+	RefreshToken string     `json:"refresh_token"`
+	Scope        string     `json:"scope"`
+	User         *data.User `json:"user,omitempty"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type passwordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type passwordResetConfirmation struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func getUserRepo(w http.ResponseWriter, r *http.Request) (*data.UserRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+	return data.NewUserRepository(store), nil
+}
+
+func getRefreshTokenRepo(w http.ResponseWriter, r *http.Request) (*data.RefreshTokenRepository, error) {
+	store, ok := r.Context().Value("memoryStore").(*data.DBTemplate)
+	if !ok || store == nil {
+		http.Error(w, "Store not found in context", http.StatusInternalServerError)
+		return nil, errors.New("store not found in context")
+	}
+	return data.NewRefreshTokenRepository(store), nil
+}
+
+// passwordError writes the response of a rejected password, it reports whether err was one.
+func passwordError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, data.ErrPasswordTooShort) || errors.Is(err, data.ErrPasswordTooLong) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+	return false
+}
+
+func Register(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	var request registrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.Register(data.User{Email: request.Email, Name: request.Name}, request.Password)
+	if err != nil {
+		if passwordError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, data.ErrInvalidEmail):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, data.ErrEmailTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/users/me")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// writeTokens issues an access token for a refresh token family and writes both tokens.
+func writeTokens(w http.ResponseWriter, user data.User, refresh data.RefreshToken, refreshToken string, includeUser bool) {
+	accessToken, _, err := signingKeys.Issue(user, refresh.FamilyID, refresh.Scope)
+	if err != nil {
+		http.Error(w, "Failed to issue access token", http.StatusInternalServerError)
+		return
+	}
+
+	response := tokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        refresh.Scope,
+	}
+	if includeUser {
+		response.User = &user
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Login checks an email and password and issues an access token and a refresh token for the account.
+func Login(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	var request credentials
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	scope, err := auth.NormalizeScope(request.Scope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.Authenticate(request.Email, request.Password)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidCredentials) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	session := data.NewSession(uuid.NewString(), user.ID, clientIP(r), r.UserAgent(), time.Now().UTC())
+	if err := sessions.Create(session); err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	refresh, refreshToken, err := refreshTokens.Issue(session, scope)
+	if err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	writeTokens(w, user, refresh, refreshToken, true)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token, while its
+// session is active. The user is read again, so role changes apply from the next refresh.
+func RefreshToken(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	var request refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	refresh, refreshToken, err := refreshTokens.Rotate(request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidRefreshToken) || errors.Is(err, data.ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+	if _, err := sessions.Touch(refresh.FamilyID, clientIP(r), time.Now().UTC()); err != nil {
+		if errors.Is(err, data.ErrSessionNotFound) || errors.Is(err, data.ErrSessionInactive) {
+			http.Error(w, data.ErrSessionInactive.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := repo.GetById(refresh.UserID)
+	if err != nil {
+		if errors.Is(err, data.ErrUserNotFound) {
+			http.Error(w, data.ErrInvalidRefreshToken.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+	writeTokens(w, user, refresh, refreshToken, false)
+}
+
+// GetJWKS publishes the public keys verifying the access tokens.
+func GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(signingKeys.JWKS())
+}
+
+func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	sessionUser, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := repo.GetById(sessionUser.ID)
+	if err != nil {
+		if errors.Is(err, data.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ChangePassword replaces the password of the current user and revokes the user's other sessions.
+func ChangePassword(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request passwordChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.ChangePassword(user.ID, request.CurrentPassword, request.NewPassword); err != nil {
+		if passwordError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, data.ErrInvalidCredentials):
+			http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		case errors.Is(err, data.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		}
+		return
+	}
+	claims, _ := currentClaims(r)
+	if _, err := revokeUserSessions(refreshTokens, user.ID, claims.SessionID); err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestPasswordReset mails a reset token to the account of an email. The response is the same
+// whether or not the account exists, so it can't be used to probe for accounts.
+func RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	mailer, ok := r.Context().Value("mailer").(mail.Mailer)
+	if !ok || mailer == nil {
+		http.Error(w, "Mailer not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	var request passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	user, token, err := repo.CreatePasswordReset(request.Email)
+	if err != nil && !errors.Is(err, data.ErrUserNotFound) {
+		http.Error(w, "Failed to request password reset", http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		body := fmt.Sprintf("Hello %s,\n\nUse this token to choose a new password, it is valid for %s:\n\n%s\n\n"+
+			"Send it with your new password to POST /password-reset/confirm. If you did not ask for a reset, ignore this mail.",
+			user.Name, data.PasswordResetValidity, token)
+		if err := mailer.Send(user.Email, "Reset your password", body); err != nil {
+			loggerFrom(r).Error("failed to send password reset mail", "user_id", user.ID, "error", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset sets a new password with a mailed reset token and revokes every session of the account.
+func ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	repo, err := getUserRepo(w, r)
+	if err != nil {
+		return
+	}
+	refreshTokens, err := getRefreshTokenRepo(w, r)
+	if err != nil {
+		return
+	}
+
+	var request passwordResetConfirmation
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	user, err := repo.ResetPassword(request.Token, request.NewPassword)
+	if err != nil {
+		if passwordError(w, err) {
+			return
+		}
+		if errors.Is(err, data.ErrInvalidResetToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	if _, err := revokeUserSessions(refreshTokens, user.ID, ""); err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
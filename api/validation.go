@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"finalproject/data"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+)
+
+// A rule checks a value, it returns the code and message of the error when the value breaks it
+// and empty strings otherwise.
+type rule[T any] func(value T) (code, message string)
+
+// validator collects the field errors of a payload.
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) add(field, code, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Code: code, Message: message})
+}
+
+// failed tells whether field already has an error.
+func (v *validator) failed(field string) bool {
+	for _, err := range v.errors {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks value against rules in order, and records the first one it breaks.
+func validate[T any](v *validator, field string, value T, rules ...rule[T]) {
+	for _, check := range rules {
+		if code, message := check(value); code != "" {
+			v.add(field, code, message)
+			return
+		}
+	}
+}
+
+var required rule[string] = func(value string) (string, string) {
+	if strings.TrimSpace(value) == "" {
+		return "required", "is required"
+	}
+	return "", ""
+}
+
+var email rule[string] = func(value string) (string, string) {
+	if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+		return "email", "must be an email address"
+	}
+	return "", ""
+}
+
+func oneOf(values ...string) rule[string] {
+	return func(value string) (string, string) {
+		if !slices.Contains(values, value) {
+			return "one_of", "must be one of " + strings.Join(values, ", ")
+		}
+		return "", ""
+	}
+}
+
+func maxLength(max int) rule[string] {
+	return func(value string) (string, string) {
+		if len([]rune(value)) > max {
+			return "max_length", fmt.Sprintf("must be at most %d characters", max)
+		}
+		return "", ""
+	}
+}
+
+func excludes(chars string) rule[string] {
+	return func(value string) (string, string) {
+		if strings.ContainsAny(value, chars) {
+			return "invalid_character", fmt.Sprintf("must not contain %q", chars)
+		}
+		return "", ""
+	}
+}
+
+func atLeast[N int | float64](min N) rule[N] {
+	return func(value N) (string, string) {
+		if value < min {
+			return "min", fmt.Sprintf("must be at least %v", min)
+		}
+		return "", ""
+	}
+}
+
+func atMost[N int | float64](max N) rule[N] {
+	return func(value N) (string, string) {
+		if value > max {
+			return "max", fmt.Sprintf("must be at most %v", max)
+		}
+		return "", ""
+	}
+}
+
+// Bounds of the columns the payloads are stored in.
+const (
+	maxPrice    = 99999999.99
+	maxQuantity = 10000
+)
+
+// validateBook checks a book to create or replace. The author and publisher are checked to
+// exist, a publisher id of 0 leaves the book without one.
+func validateBook(store *data.DBTemplate, book data.Book) ([]FieldError, error) {
+	var v validator
+	validate(&v, "title", book.Title, required, maxLength(255))
+	validate(&v, "author.id", book.Author.ID, atLeast(1))
+	validate(&v, "publisher.id", book.Publisher.ID, atLeast(0))
+	if book.PublishedAt.IsZero() {
+		v.add("published_at", "required", "is required")
+	}
+	validate(&v, "price", book.Price, atLeast(0.0), atMost(maxPrice))
+	validate(&v, "stock", book.Stock, atLeast(0))
+	validate(&v, "weight_grams", book.WeightGrams, atLeast(0))
+	validate(&v, "tax_category", book.TaxCategory, maxLength(50))
+	// Genres are stored joined by commas.
+	for i, genre := range book.Genres {
+		validate(&v, fmt.Sprintf("genres[%d]", i), genre, required, excludes(","), maxLength(100))
+	}
+
+	if !v.failed("author.id") {
+		if _, err := data.NewAuthorRepository(store).GetById(book.Author.ID); errors.Is(err, data.ErrAuthorNotFound) {
+			v.add("author.id", "not_found", "no author has this id")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if !v.failed("publisher.id") && book.Publisher.ID != 0 {
+		if _, err := data.NewPublisherRepository(store).GetById(book.Publisher.ID); errors.Is(err, data.ErrPublisherNotFound) {
+			v.add("publisher.id", "not_found", "no publisher has this id")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return v.errors, nil
+}
+
+func validateAuthor(author data.Author) []FieldError {
+	var v validator
+	validate(&v, "first_name", author.FirstName, required, maxLength(100))
+	validate(&v, "last_name", author.LastName, required, maxLength(100))
+	validate(&v, "bio", author.Bio, maxLength(10000))
+	return v.errors
+}
+
+func validatePublisher(publisher data.Publisher) []FieldError {
+	var v validator
+	validate(&v, "name", publisher.Name, required, maxLength(255))
+	validate(&v, "country", publisher.Country, maxLength(100))
+	validate(&v, "website", publisher.Website, maxLength(255))
+	return v.errors
+}
+
+// validateCustomer checks a customer to create or replace and its address.
+func validateCustomer(customer data.Customer) []FieldError {
+	var v validator
+	validate(&v, "name", customer.Name, required, maxLength(150))
+	validate(&v, "email", customer.Email, required, maxLength(150), email)
+	validate(&v, "address.street", customer.Address.Street, required, maxLength(255))
+	validate(&v, "address.city", customer.Address.City, required, maxLength(100))
+	validate(&v, "address.state", customer.Address.State, required, maxLength(100))
+	validate(&v, "address.postal_code", customer.Address.PostalCode, required, maxLength(20))
+	validate(&v, "address.country", customer.Address.Country, required, maxLength(100))
+	return v.errors
+}
+
+// validateCheckout checks the order to place. Whether the books and the customer exist is left to
+// the checkout, which reads them in its transaction anyway.
+func validateCheckout(request data.CheckoutRequest) []FieldError {
+	var v validator
+	validate(&v, "customer_id", request.CustomerID, atLeast(0))
+	for i, item := range request.Items {
+		validate(&v, fmt.Sprintf("items[%d].book_id", i), item.BookID, atLeast(1))
+		validate(&v, fmt.Sprintf("items[%d].quantity", i), item.Quantity, atLeast(1), atMost(maxQuantity))
+	}
+	for i, code := range request.PromotionCodes {
+		validate(&v, fmt.Sprintf("promotion_codes[%d]", i), code, required, maxLength(50))
+	}
+	return v.errors
+}
+
+// validateTransition checks an order status change. Whether the move is allowed from the current
+// status is left to the transition.
+func validateTransition(request transitionRequest) []FieldError {
+	var v validator
+	validate(&v, "status", request.Status, required, oneOf(data.OrderStatuses...))
+	return v.errors
+}
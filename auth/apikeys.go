@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// AnyMethod and AnyRoute widen an API key scope to every method or every route.
+const (
+	AnyMethod = "*"
+	AnyRoute  = "*"
+)
+
+var scopeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", AnyMethod}
+
+// ValidateAPIKeyScope checks a scope of the form "METHOD /route/{pattern}", as in the route table of the api package.
+func ValidateAPIKeyScope(scope string) error {
+	method, route, ok := strings.Cut(scope, " ")
+	if !ok {
+		return fmt.Errorf("scope %q must be a method and a route, like \"GET /books\"", scope)
+	}
+	known := false
+	for _, m := range scopeMethods {
+		known = known || m == method
+	}
+	if !known {
+		return fmt.Errorf("scope %q has an unknown method", scope)
+	}
+	if route != AnyRoute && !strings.HasPrefix(route, "/") {
+		return fmt.Errorf("scope %q must have a route starting with /", scope)
+	}
+	return nil
+}
+
+// APIKeyScopeAllows reports whether a request to a route pattern is within the scopes of a key.
+// A GET scope also allows HEAD, as the routes do.
+func APIKeyScopeAllows(scopes []string, method string, pattern string) bool {
+	for _, scope := range scopes {
+		scopeMethod, route, _ := strings.Cut(scope, " ")
+		methodMatches := scopeMethod == AnyMethod || scopeMethod == method || (scopeMethod == "GET" && method == "HEAD")
+		if methodMatches && (route == AnyRoute || route == pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAllowedIP checks an address or CIDR range of an API key allowlist.
+func ValidateAllowedIP(entry string) error {
+	if _, err := netip.ParsePrefix(entry); err == nil {
+		return nil
+	}
+	if _, err := netip.ParseAddr(entry); err == nil {
+		return nil
+	}
+	return errors.New("allowed IP " + entry + " is neither an address nor a CIDR range")
+}
+
+// IPAllowed reports whether an address is in an allowlist, an empty allowlist allows every address.
+func IPAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, entry := range allowed {
+		if prefix, err := netip.ParsePrefix(entry); err == nil && prefix.Contains(addr) {
+			return true
+		}
+		if single, err := netip.ParseAddr(entry); err == nil && single.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+// Package auth issues and verifies the signed access tokens of the API. Tokens are RS256 JWTs,
+// the public keys are published as a JWKS so other services can verify tokens offline.
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	Issuer         = "finalproject"
+	AccessTokenTTL = 15 * time.Minute
+	// clockLeeway tolerates small clock differences with the services verifying the tokens.
+	clockLeeway = 30 * time.Second
+
+	// ScopeRead allows safe requests (GET, HEAD, OPTIONS), ScopeWrite allows every other method and implies ScopeRead.
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// DefaultScope is granted when a login asks for no scope.
+var DefaultScope = ScopeRead + " " + ScopeWrite
+
+var (
+	ErrInvalidToken = errors.New("access token is invalid")
+	ErrTokenExpired = errors.New("access token has expired")
+	ErrUnknownScope = errors.New("scope must be made of read and write")
+)
+
+// Claims are the claims of an access token. SessionID is the refresh token family the token
+// was issued for, it stays the same across refreshes.
+type Claims struct {
+	Issuer     string   `json:"iss"`
+	Subject    string   `json:"sub"`
+	IssuedAt   int64    `json:"iat"`
+	NotBefore  int64    `json:"nbf"`
+	ExpiresAt  int64    `json:"exp"`
+	ID         string   `json:"jti"`
+	SessionID  string   `json:"sid"`
+	Email      string   `json:"email"`
+	CustomerID int      `json:"customer_id,omitempty"`
+	Roles      []string `json:"roles"`
+	Scope      string   `json:"scope"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// HasScope reports whether the token was granted a scope.
+func (c Claims) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeScope checks a requested scope and returns it in canonical form, DefaultScope when empty.
+func NormalizeScope(scope string) (string, error) {
+	read, write := false, false
+	for _, s := range strings.Fields(scope) {
+		switch s {
+		case ScopeRead:
+			read = true
+		case ScopeWrite:
+			write = true
+		default:
+			return "", ErrUnknownScope
+		}
+	}
+	if read && !write {
+		return ScopeRead, nil
+	}
+	return DefaultScope, nil
+}
+
+func encodeSegment(v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func sign(claims Claims, kid string, key *rsa.PrivateKey) (string, error) {
+	encodedHeader, err := encodeSegment(header{Algorithm: "RS256", Type: "JWT", KeyID: kid})
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + encodedClaims
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verify checks the signature and the time claims of a token, publicKey resolves a kid to its key.
+func verify(token string, publicKey func(kid string) (*rsa.PublicKey, bool), now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var h header
+	// Only RS256 is accepted, whatever the token claims, so "none" or HMAC tokens are rejected.
+	if err := json.Unmarshal(rawHeader, &h); err != nil || h.Algorithm != "RS256" {
+		return Claims{}, ErrInvalidToken
+	}
+	key, ok := publicKey(h.KeyID)
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(rawClaims, &claims); err != nil || claims.Issuer != Issuer || claims.Subject == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	if now.Add(clockLeeway).Unix() < claims.NotBefore {
+		return Claims{}, ErrInvalidToken
+	}
+	if now.Add(-clockLeeway).Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return claims, nil
+}
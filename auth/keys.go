@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"finalproject/data"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KeyRotationInterval = 30 * 24 * time.Hour
+	// keyRefreshInterval is how often the keys are reloaded, to pick up the rotations of other instances.
+	keyRefreshInterval = time.Hour
+	// retiredKeyRetention keeps a retired key published while tokens it signed may be valid. An instance
+	// may keep signing with it until its next refresh.
+	retiredKeyRetention = AccessTokenTTL + keyRefreshInterval + clockLeeway
+	// unknownKidReloadInterval throttles the reloads triggered by tokens with an unknown kid.
+	unknownKidReloadInterval = 10 * time.Second
+	keyBits                  = 2048
+)
+
+type signingKey struct {
+	kid       string
+	key       *rsa.PrivateKey
+	createdAt time.Time
+}
+
+// KeyManager holds the signing keys, which are shared by every instance through the database.
+type KeyManager struct {
+	repo *data.SigningKeyRepository
+
+	mu         sync.RWMutex
+	current    signingKey
+	published  []signingKey
+	lastReload time.Time
+}
+
+// JWK is an RSA public key in JSON Web Key form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeyManager loads the signing keys, a first key is created when there is none.
+func NewKeyManager(repo *data.SigningKeyRepository) (*KeyManager, error) {
+	manager := &KeyManager{repo: repo}
+	if err := manager.Reload(); err != nil {
+		return nil, err
+	}
+	if manager.current.key == nil {
+		if err := manager.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return manager, nil
+}
+
+// Reload reads the published keys from the database.
+func (m *KeyManager) Reload() error {
+	stored, err := m.repo.GetPublished(time.Now().UTC().Add(-retiredKeyRetention))
+	if err != nil {
+		return err
+	}
+
+	var current signingKey
+	published := make([]signingKey, 0, len(stored))
+	for _, s := range stored {
+		block, _ := pem.Decode([]byte(s.PrivateKey))
+		if block == nil {
+			return fmt.Errorf("signing key %s is not PEM encoded", s.Kid)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", s.Kid, err)
+		}
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return fmt.Errorf("signing key %s is not an RSA key", s.Kid)
+		}
+		key := signingKey{kid: s.Kid, key: rsaKey, createdAt: s.CreatedAt}
+		published = append(published, key)
+		if s.RetiredAt == nil && current.key == nil {
+			current = key
+		}
+	}
+
+	m.mu.Lock()
+	m.current = current
+	m.published = published
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// Rotate creates a new signing key and retires the current one, which stays published for a while.
+func (m *KeyManager) Rotate() error {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	stored := data.SigningKey{
+		Kid:        uuid.NewString(),
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := m.repo.Rotate(stored); err != nil {
+		return err
+	}
+	return m.Reload()
+}
+
+func (m *KeyManager) publicKey(kid string) (*rsa.PublicKey, bool) {
+	m.mu.RLock()
+	for _, k := range m.published {
+		if k.kid == kid {
+			m.mu.RUnlock()
+			return &k.key.PublicKey, true
+		}
+	}
+	stale := time.Since(m.lastReload) > unknownKidReloadInterval
+	m.mu.RUnlock()
+
+	// The key may have been created by another instance since the last reload.
+	if !stale {
+		return nil, false
+	}
+	if err := m.Reload(); err != nil {
+		log.Println("Error reloading signing keys:", err)
+		return nil, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range m.published {
+		if k.kid == kid {
+			return &k.key.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+// Issue signs an access token for a user, sessionID is the refresh token family of the login.
+func (m *KeyManager) Issue(user data.User, sessionID string, scope string) (string, Claims, error) {
+	m.mu.RLock()
+	current := m.current
+	m.mu.RUnlock()
+	if current.key == nil {
+		return "", Claims{}, errors.New("no signing key available")
+	}
+
+	now := time.Now().UTC()
+	claims := Claims{
+		Issuer:     Issuer,
+		Subject:    strconv.Itoa(user.ID),
+		IssuedAt:   now.Unix(),
+		NotBefore:  now.Unix(),
+		ExpiresAt:  now.Add(AccessTokenTTL).Unix(),
+		ID:         uuid.NewString(),
+		SessionID:  sessionID,
+		Email:      user.Email,
+		CustomerID: user.CustomerID,
+		Roles:      user.Roles,
+		Scope:      scope,
+	}
+	token, err := sign(claims, current.kid, current.key)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	return token, claims, nil
+}
+
+// Verify checks an access token and returns its claims.
+func (m *KeyManager) Verify(token string) (Claims, error) {
+	return verify(token, m.publicKey, time.Now())
+}
+
+// JWKS returns the public keys of the published signing keys.
+func (m *KeyManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	set := JWKSet{Keys: make([]JWK, 0, len(m.published))}
+	for _, k := range m.published {
+		public := k.key.PublicKey
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: "RS256",
+			KeyID:     k.kid,
+			Modulus:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		})
+	}
+	return set
+}
+
+// StartKeyRotation reloads the keys periodically, rotates the current one once it is older than
+// KeyRotationInterval and removes the keys that no longer need to be published.
+func StartKeyRotation(m *KeyManager) {
+	ticker := time.NewTicker(keyRefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.Reload(); err != nil {
+			log.Println("Error reloading signing keys:", err)
+			continue
+		}
+		m.mu.RLock()
+		due := m.current.key == nil || time.Since(m.current.createdAt) > KeyRotationInterval
+		m.mu.RUnlock()
+		if due {
+			if err := m.Rotate(); err != nil {
+				log.Println("Error rotating signing key:", err)
+				continue
+			}
+			log.Println("Rotated the token signing key")
+		}
+		if _, err := m.repo.DeleteRetiredBefore(time.Now().UTC().Add(-retiredKeyRetention)); err != nil {
+			log.Println("Error removing retired signing keys:", err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"finalproject/data"
+	"slices"
+	"sort"
+)
+
+const (
+	RoleAdmin           = "admin"
+	RoleCatalogEditor   = "catalog-editor"
+	RoleCustomerSupport = "customer-support"
+	RoleCustomer        = data.RoleCustomer
+)
+
+// A Permission allows a group of privileged operations. Operations needing no permission are open to every authenticated user.
+type Permission string
+
+const (
+	PermissionCatalogWrite    Permission = "catalog:write"
+	PermissionPricingWrite    Permission = "pricing:write"
+	PermissionOrdersManage    Permission = "orders:manage"
+	PermissionCustomersManage Permission = "customers:manage"
+	PermissionReturnsManage   Permission = "returns:manage"
+	PermissionReviewsManage   Permission = "reviews:manage"
+	PermissionSessionsManage  Permission = "sessions:manage"
+	PermissionUsersManage     Permission = "users:manage"
+	PermissionAPIKeysManage   Permission = "apikeys:manage"
+)
+
+// RolePermissions lists the permissions granted by each role. The admin role is granted every permission.
+var RolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermissionCatalogWrite, PermissionPricingWrite, PermissionOrdersManage, PermissionCustomersManage,
+		PermissionReturnsManage, PermissionReviewsManage, PermissionSessionsManage, PermissionUsersManage,
+		PermissionAPIKeysManage,
+	},
+	RoleCatalogEditor: {PermissionCatalogWrite},
+	RoleCustomerSupport: {
+		PermissionOrdersManage, PermissionCustomersManage, PermissionReturnsManage, PermissionReviewsManage,
+		PermissionSessionsManage,
+	},
+	RoleCustomer: {},
+}
+
+func IsKnownRole(role string) bool {
+	_, exists := RolePermissions[role]
+	return exists
+}
+
+// HasPermission reports whether any of the roles grants a permission.
+func HasPermission(roles []string, permission Permission) bool {
+	for _, role := range roles {
+		if slices.Contains(RolePermissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesWith returns the roles granting a permission, sorted.
+func RolesWith(permission Permission) []string {
+	roles := []string{}
+	for role, permissions := range RolePermissions {
+		if slices.Contains(permissions, permission) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
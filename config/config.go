@@ -0,0 +1,381 @@
+// Package config loads the settings of the server. Each setting is read, by increasing precedence,
+// from its default, the JSON config file, its environment variable and its command line flag.
+// Secrets can also be read from a file named by the same setting with a _file suffix.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type ServerConfig struct {
+	Addr string
+	// MetricsAddr is the internal address serving /metrics, apart from the public API.
+	MetricsAddr     string
+	RequestTimeout  time.Duration
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration
+}
+
+type DatabaseConfig struct {
+	URL string
+	// Password replaces the password of the URL when set, so the URL itself needn't be secret.
+	Password string
+}
+
+type StorageConfig struct {
+	BlobDir string
+}
+
+type MailConfig struct {
+	// Outbox is the file the mails are written to, for development. When empty the mails are only logged
+	// as sent, without their body.
+	Outbox string
+}
+
+type ReportsConfig struct {
+	Dir      string
+	Interval time.Duration
+}
+
+type LoggingConfig struct {
+	Level         string
+	Output        string
+	File          string
+	RequestOutput string
+	RequestFile   string
+}
+
+type RateLimitConfig struct {
+	Store string
+}
+
+type TracingConfig struct {
+	Exporter string
+}
+
+type Config struct {
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Storage   StorageConfig
+	Mail      MailConfig
+	Reports   ReportsConfig
+	Logging   LoggingConfig
+	RateLimit RateLimitConfig
+	Tracing   TracingConfig
+
+	// sources tells where the value of each setting came from, by key.
+	sources map[string]string
+}
+
+// setting describes one setting: its key in the config file, its environment variable and its flag.
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	// field returns the *string or *time.Duration holding the setting.
+	field func(c *Config) any
+}
+
+var settings = []setting{
+	{"server.addr", "SERVER_ADDR", "addr", "address the server listens on", false, func(c *Config) any { return &c.Server.Addr }},
+	{"server.metrics_addr", "METRICS_ADDR", "metrics-addr", "internal address serving /metrics", false, func(c *Config) any { return &c.Server.MetricsAddr }},
+	{"server.request_timeout", "REQUEST_TIMEOUT", "request-timeout", "deadline of the database work of a request", false, func(c *Config) any { return &c.Server.RequestTimeout }},
+	{"server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "shutdown-timeout", "time given to the ongoing requests on shutdown", false, func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"server.drain_delay", "SHUTDOWN_DRAIN_DELAY", "drain-delay", "time /readyz fails before the shutdown starts", false, func(c *Config) any { return &c.Server.DrainDelay }},
+	{"database.url", "DATABASE_URL", "database-url", "Postgres connection URL", true, func(c *Config) any { return &c.Database.URL }},
+	{"database.password", "DATABASE_PASSWORD", "database-password", "Postgres password, replaces the one of the URL", true, func(c *Config) any { return &c.Database.Password }},
+	{"storage.blob_dir", "BLOB_DIR", "blob-dir", "directory of the book covers", false, func(c *Config) any { return &c.Storage.BlobDir }},
+	{"mail.outbox", "MAIL_OUTBOX", "mail-outbox", "file the mails are written to in development, none logs them as sent", false, func(c *Config) any { return &c.Mail.Outbox }},
+	{"reports.dir", "REPORTS_DIR", "reports-dir", "directory of the sales reports", false, func(c *Config) any { return &c.Reports.Dir }},
+	{"reports.interval", "REPORTS_INTERVAL", "reports-interval", "time between two sales reports", false, func(c *Config) any { return &c.Reports.Interval }},
+	{"logging.level", "LOG_LEVEL", "log-level", "application log level: debug, info, warn or error", false, func(c *Config) any { return &c.Logging.Level }},
+	{"logging.output", "LOG_OUTPUT", "log-output", "application log destination: file, stdout or both", false, func(c *Config) any { return &c.Logging.Output }},
+	{"logging.file", "LOG_FILE", "log-file", "application log file", false, func(c *Config) any { return &c.Logging.File }},
+	{"logging.request_output", "REQUEST_LOG_OUTPUT", "request-log-output", "request log destination: file, stdout or both", false, func(c *Config) any { return &c.Logging.RequestOutput }},
+	{"logging.request_file", "REQUEST_LOG_FILE", "request-log-file", "request log file", false, func(c *Config) any { return &c.Logging.RequestFile }},
+	{"rate_limit.store", "RATE_LIMIT_STORE", "rate-limit-store", "rate limit buckets store: memory or postgres", false, func(c *Config) any { return &c.RateLimit.Store }},
+	{"tracing.exporter", "OTEL_TRACES_EXPORTER", "trace-exporter", "span exporter: none, otlp or stdout", false, func(c *Config) any { return &c.Tracing.Exporter }},
+}
+
+// Default returns the settings used when nothing overrides them. The database URL carries no
+// password, set database.password (or DATABASE_PASSWORD, or DATABASE_PASSWORD_FILE).
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			MetricsAddr:     "127.0.0.1:9090",
+			RequestTimeout:  5 * time.Second,
+			ShutdownTimeout: 5 * time.Second,
+			DrainDelay:      5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			URL: "postgres://postgres@localhost:5432/finalproject?sslmode=disable",
+		},
+		Storage: StorageConfig{BlobDir: "storage"},
+		Reports: ReportsConfig{Dir: "output-reports", Interval: 24 * time.Second},
+		Logging: LoggingConfig{
+			Level:         "info",
+			Output:        "stdout",
+			File:          "app.log",
+			RequestOutput: "file",
+			RequestFile:   "requests.log",
+		},
+		RateLimit: RateLimitConfig{Store: "memory"},
+		Tracing:   TracingConfig{Exporter: "none"},
+	}
+}
+
+// Load builds the configuration from the defaults, the config file (-config or CONFIG_FILE),
+// the environment and the command line arguments, then validates it.
+func Load(args []string) (*Config, error) {
+	c := Default()
+	c.sources = map[string]string{}
+	for _, s := range settings {
+		c.sources[s.key] = "default"
+	}
+
+	flags := flag.NewFlagSet("finalproject", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "JSON config file")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.flag] = flags.String(s.flag, "", s.usage+" (env "+s.env+")")
+		if s.secret {
+			flagValues[s.flag+"-file"] = flags.String(s.flag+"-file", "", "file holding the "+s.usage+" (env "+s.env+"_FILE)")
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	if *configFile != "" {
+		if err := c.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	visited := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { visited[f.Name] = true })
+	for _, s := range settings {
+		if visited[s.flag] {
+			if err := c.set(s, *flagValues[s.flag], "flag -"+s.flag); err != nil {
+				return nil, err
+			}
+		}
+		if s.secret && visited[s.flag+"-file"] {
+			if err := c.setFromFile(s, *flagValues[s.flag+"-file"], "flag -"+s.flag+"-file"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadFile reads a JSON file of nested objects, {"server": {"addr": ":8080"}}. Unknown keys are
+// refused, so a misspelled setting doesn't go unnoticed.
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(content, &tree); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flatten("", tree, values); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	source := "file " + path
+	for _, s := range settings {
+		if value, ok := values[s.key]; ok {
+			if err := c.set(s, value, source); err != nil {
+				return err
+			}
+			delete(values, s.key)
+		}
+		if value, ok := values[s.key+"_file"]; ok && s.secret {
+			if err := c.setFromFile(s, value, source); err != nil {
+				return err
+			}
+			delete(values, s.key+"_file")
+		}
+	}
+	for key := range values {
+		return fmt.Errorf("config file %s: unknown setting %q", path, key)
+	}
+	return nil
+}
+
+// flatten turns nested objects into dotted keys.
+func flatten(prefix string, tree map[string]any, values map[string]string) error {
+	for name, value := range tree {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flatten(key, v, values); err != nil {
+				return err
+			}
+		case string:
+			values[key] = v
+		case float64, bool:
+			values[key] = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("setting %q must be a string, a number or an object", key)
+		}
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := c.set(s, value, "env "+s.env); err != nil {
+				return err
+			}
+		}
+		if path, ok := os.LookupEnv(s.env + "_FILE"); ok && s.secret {
+			if err := c.setFromFile(s, path, "env "+s.env+"_FILE"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) set(s setting, value string, source string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = strings.TrimSpace(value)
+	case *time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s (%s): %q is not a duration such as 5s or 1m", s.key, source, value)
+		}
+		*field = d
+	}
+	c.sources[s.key] = source
+	return nil
+}
+
+// setFromFile sets a secret to the content of a file, without its trailing newline.
+func (c *Config) setFromFile(s setting, path string, source string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s (%s): %w", s.key, source, err)
+	}
+	return c.set(s, strings.TrimRight(string(content), "\r\n"), source+" "+path)
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if strings.EqualFold(value, a) {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %q must be one of %s", key, value, strings.Join(allowed, ", ")))
+	}
+
+	_, _, err := net.SplitHostPort(c.Server.Addr)
+	check(err == nil, "server.addr: %q must be host:port or :port", c.Server.Addr)
+	_, _, err = net.SplitHostPort(c.Server.MetricsAddr)
+	check(err == nil, "server.metrics_addr: %q must be host:port or :port", c.Server.MetricsAddr)
+	check(c.Server.MetricsAddr != c.Server.Addr, "server.metrics_addr must differ from server.addr")
+	check(c.Server.RequestTimeout > 0, "server.request_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay can't be negative")
+
+	u, err := url.Parse(c.Database.URL)
+	check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") && u.Host != "",
+		"database.url must be a postgres://user@host:port/database URL")
+
+	check(c.Storage.BlobDir != "", "storage.blob_dir can't be empty")
+	check(c.Reports.Dir != "", "reports.dir can't be empty")
+	check(c.Reports.Interval >= time.Second, "reports.interval must be at least 1s")
+
+	oneOf("logging.level", c.Logging.Level, "debug", "info", "warn", "error")
+	oneOf("logging.output", c.Logging.Output, "file", "stdout", "both")
+	oneOf("logging.request_output", c.Logging.RequestOutput, "file", "stdout", "both")
+	check(c.Logging.File != "", "logging.file can't be empty")
+	check(c.Logging.RequestFile != "", "logging.request_file can't be empty")
+	oneOf("rate_limit.store", c.RateLimit.Store, "memory", "postgres")
+	oneOf("tracing.exporter", c.Tracing.Exporter, "none", "otlp", "stdout")
+
+	return errors.Join(errs...)
+}
+
+// DatabaseURL returns the connection URL with the configured password.
+func (c *Config) DatabaseURL() string {
+	if c.Database.Password == "" {
+		return c.Database.URL
+	}
+	u, err := url.Parse(c.Database.URL)
+	if err != nil {
+		return c.Database.URL
+	}
+	username := "postgres"
+	if u.User != nil {
+		username = u.User.Username()
+	}
+	u.User = url.UserPassword(username, c.Database.Password)
+	return u.String()
+}
+
+// Print writes the effective settings and where each one came from. Secrets are redacted, and
+// so is the password of the database URL.
+func (c *Config) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range settings {
+		var value string
+		switch field := s.field(c).(type) {
+		case *string:
+			value = *field
+		case *time.Duration:
+			value = field.String()
+		}
+		switch {
+		case s.key == "database.url":
+			if u, err := url.Parse(value); err == nil {
+				value = u.Redacted()
+			}
+		case s.secret && value != "":
+			value = "[REDACTED]"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.key, value, c.sources[s.key])
+	}
+	tw.Flush()
+}
@@ -4,16 +4,9 @@ import (
 	"errors"
 )
 
-var m = make(map[string]any)
-
+// GetDAO returns the repository of an entity, bound to dbTemplate. Repositories are thin wrappers
+// around their template, so one is built per call: it then runs in the request context of the template.
 func GetDAO[T EntityType](name string, dbTemplate *DBTemplate) (IDAO[T], error) {
-	if dao, exists := m[name]; exists {
-		if typedDAO, ok := dao.(IDAO[T]); ok {
-			return typedDAO, nil
-		}
-		return nil, errors.New("repository exists with a different type")
-	}
-
 	var dao IDAO[T]
 	switch name {
 	case "book":
@@ -28,10 +21,30 @@ func GetDAO[T EntityType](name string, dbTemplate *DBTemplate) (IDAO[T], error)
 		if authorRepo, ok := any(NewAuthorRepository(dbTemplate)).(IDAO[T]); ok {
 			dao = authorRepo
 		}
+	case "publisher":
+		if publisherRepo, ok := any(NewPublisherRepository(dbTemplate)).(IDAO[T]); ok {
+			dao = publisherRepo
+		}
 	case "order":
 		if orderRepo, ok := any(NewOrderRepository(dbTemplate)).(IDAO[T]); ok {
 			dao = orderRepo
 		}
+	case "review":
+		if reviewRepo, ok := any(NewReviewRepository(dbTemplate)).(IDAO[T]); ok {
+			dao = reviewRepo
+		}
+	case "promotion":
+		if promotionRepo, ok := any(NewPromotionRepository(dbTemplate)).(IDAO[T]); ok {
+			dao = promotionRepo
+		}
+	case "taxRule":
+		if taxRuleRepo, ok := any(NewTaxRuleRepository(dbTemplate)).(IDAO[T]); ok {
+			dao = taxRuleRepo
+		}
+	case "shippingZone":
+		if shippingZoneRepo, ok := any(NewShippingZoneRepository(dbTemplate)).(IDAO[T]); ok {
+			dao = shippingZoneRepo
+		}
 	default:
 		return nil, errors.New("invalid repository")
 	}
@@ -39,7 +52,5 @@ func GetDAO[T EntityType](name string, dbTemplate *DBTemplate) (IDAO[T], error)
 	if dao == nil {
 		return nil, errors.New("failed to create repository")
 	}
-
-	m[name] = dao
 	return dao, nil
 }
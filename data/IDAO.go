@@ -1,7 +1,7 @@
 package data
 
 type EntityType interface {
-	Book | Author | Customer | Order
+	Book | Author | Publisher | Customer | Order | Review | Promotion | TaxRule | ShippingZone
 }
 
 type IDAO[T EntityType] interface {
@@ -0,0 +1,208 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// APIKeyMarker starts every API key, so keys are told apart from access tokens and found by secret scanners.
+// A key reads bsk_<prefix>_<secret>, the prefix identifies the key and is not secret.
+const APIKeyMarker = "bsk_"
+
+// apiKeyLastUsedResolution limits how often a key is written to when it is used.
+const apiKeyLastUsedResolution = time.Minute
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidAPIKey  = errors.New("API key is invalid, expired or revoked")
+	ErrAPIKeyRevoked  = errors.New("API key is revoked")
+)
+
+const apiKeySelectQuery = `
+		SELECT id, name, prefix, secret_hash, COALESCE(previous_secret_hash, '') AS previous_secret_hash,
+		       previous_secret_expires_at, roles, scopes, allowed_ips, COALESCE(created_by, 0) AS created_by,
+		       created_at, expires_at, rotated_at, last_used_at, last_used_ip, revoked_at
+		FROM api_keys`
+
+type APIKeyRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewAPIKeyRepository(dbTemplate *DBTemplate) *APIKeyRepository {
+	return &APIKeyRepository{
+		dbTemplate: dbTemplate.Repository("api_key"),
+	}
+}
+
+func randomString(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func newAPIKeySecret(prefix string) (string, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	return APIKeyMarker + prefix + "_" + secret, nil
+}
+
+// Create stores a new key and returns it with its full secret, which can't be read again.
+func (repo *APIKeyRepository) Create(key APIKey) (APIKey, string, error) {
+	rawPrefix := make([]byte, 6)
+	if _, err := rand.Read(rawPrefix); err != nil {
+		return APIKey{}, "", err
+	}
+	key.Prefix = hex.EncodeToString(rawPrefix)
+	secret, err := newAPIKeySecret(key.Prefix)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	key.SecretHash = hashToken(secret)
+	key.CreatedAt = time.Now().UTC()
+
+	query := `
+		INSERT INTO api_keys (name, prefix, secret_hash, roles, scopes, allowed_ips, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), $8, $9) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, key.Name, key.Prefix, key.SecretHash, key.Roles, key.Scopes,
+		key.AllowedIPs, key.CreatedBy, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	key.ID = id
+	return key, secret, nil
+}
+
+func (repo *APIKeyRepository) GetById(id int) (APIKey, error) {
+	key, err := QueryStruct[APIKey](repo.dbTemplate, apiKeySelectQuery+`
+		WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIKey{}, ErrAPIKeyNotFound
+		}
+		return APIKey{}, err
+	}
+	return *key, nil
+}
+
+func (repo *APIKeyRepository) GetAll() ([]APIKey, error) {
+	return QueryStructs[APIKey](repo.dbTemplate, apiKeySelectQuery+`
+		ORDER BY id`)
+}
+
+// Rotate replaces the secret of a key. The previous secret stays valid for gracePeriod, so the
+// integration can switch to the new one without downtime.
+func (repo *APIKeyRepository) Rotate(id int, gracePeriod time.Duration) (APIKey, string, error) {
+	var rotated APIKey
+	var secret string
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		key, err := QueryStruct[APIKey](tx, apiKeySelectQuery+`
+			WHERE id = $1
+			FOR UPDATE`, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrAPIKeyNotFound
+			}
+			return err
+		}
+		if key.RevokedAt != nil {
+			return ErrAPIKeyRevoked
+		}
+
+		secret, err = newAPIKeySecret(key.Prefix)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		previousExpiresAt := now.Add(gracePeriod)
+		key.PreviousSecretHash = key.SecretHash
+		key.PreviousSecretExpiresAt = &previousExpiresAt
+		key.SecretHash = hashToken(secret)
+		key.RotatedAt = &now
+		if _, err := ExecuteUpdateOrDelete(tx, `
+			UPDATE api_keys SET secret_hash = $1, previous_secret_hash = $2, previous_secret_expires_at = $3, rotated_at = $4
+			WHERE id = $5`, key.SecretHash, key.PreviousSecretHash, key.PreviousSecretExpiresAt, key.RotatedAt, id); err != nil {
+			return err
+		}
+		rotated = *key
+		return nil
+	})
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	return rotated, secret, nil
+}
+
+func (repo *APIKeyRepository) Revoke(id int) error {
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1)
+		WHERE id = $2`, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// CountActive counts the keys that are neither revoked nor expired.
+func (repo *APIKeyRepository) CountActive() (int, error) {
+	count, err := QueryStruct[int](repo.dbTemplate, `
+		SELECT COUNT(*) FROM api_keys
+		WHERE revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $1)`, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return *count, nil
+}
+
+// Verify returns the key of a secret, when the key is neither expired nor revoked.
+func (repo *APIKeyRepository) Verify(secret string, now time.Time) (APIKey, error) {
+	rest, found := strings.CutPrefix(secret, APIKeyMarker)
+	prefix, _, separated := strings.Cut(rest, "_")
+	if !found || !separated {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	key, err := QueryStruct[APIKey](repo.dbTemplate, apiKeySelectQuery+`
+		WHERE prefix = $1`, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIKey{}, ErrInvalidAPIKey
+		}
+		return APIKey{}, err
+	}
+
+	hash := []byte(hashToken(secret))
+	current := subtle.ConstantTimeCompare(hash, []byte(key.SecretHash)) == 1
+	previous := key.PreviousSecretHash != "" && key.PreviousSecretExpiresAt != nil && now.Before(*key.PreviousSecretExpiresAt) &&
+		subtle.ConstantTimeCompare(hash, []byte(key.PreviousSecretHash)) == 1
+	if !current && !previous {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+	return *key, nil
+}
+
+// RecordUse stores when and from where a key was last used, at most once a minute per address.
+func (repo *APIKeyRepository) RecordUse(key APIKey, clientIP string, now time.Time) error {
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyLastUsedResolution && key.LastUsedIP == clientIP {
+		return nil
+	}
+	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, `
+		UPDATE api_keys SET last_used_at = $1, last_used_ip = $2
+		WHERE id = $3`, now, clientIP, key.ID)
+	return err
+}
@@ -5,13 +5,15 @@ import (
 	"database/sql"
 )
 
+var ErrAuthorNotFound = errors.New("author not found")
+
 type AuthorRepository struct {
 	dbTemplate *DBTemplate
 }
 
 func NewAuthorRepository(dbTemplate *DBTemplate) *AuthorRepository {
 	return &AuthorRepository{
-		dbTemplate: dbTemplate,
+		dbTemplate: dbTemplate.Repository("author"),
 	}
 }
 
@@ -35,7 +37,7 @@ func (repo *AuthorRepository) GetById(id int) (Author, error) {
 	author, err := QueryStruct[Author](repo.dbTemplate, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Author{}, errors.New("author not found")
+			return Author{}, ErrAuthorNotFound
 		}
 		return Author{}, err
 	}
@@ -61,7 +63,7 @@ func (repo *AuthorRepository) Delete(id int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("author not found")
+		return ErrAuthorNotFound
 	}
 	return nil
 }
@@ -0,0 +1,91 @@
+package data
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore abstracts where binary objects such as book covers are kept so the
+// filesystem backend can be swapped for an object store without touching handlers.
+type BlobStore interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (io.ReadSeekCloser, error)
+	Delete(key string) error
+}
+
+type FileSystemBlobStore struct {
+	root string
+}
+
+func NewFileSystemBlobStore(root string) (*FileSystemBlobStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &FileSystemBlobStore{root: root}, nil
+}
+
+func (store *FileSystemBlobStore) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(store.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put writes to a temporary file first and renames it into place, so readers
+// never observe a partially written blob.
+func (store *FileSystemBlobStore) Put(key string, r io.Reader) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (store *FileSystemBlobStore) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+func (store *FileSystemBlobStore) Delete(key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -2,44 +2,89 @@ package data
 
 import (
 	"errors"
+	"sort"
 	"strings"
 	"database/sql"
 )
 
+const bookSelectQuery = `
+		SELECT b.id, b.title, b.genres, b.published_at, b.price, b.stock, b.rating_average, b.rating_count, b.tax_category, b.weight_grams,
+		       a.id AS "author.id", a.first_name AS "author.first_name", a.last_name AS "author.last_name", a.bio AS "author.bio",
+		       COALESCE(p.id, 0) AS "publisher.id", COALESCE(p.name, '') AS "publisher.name",
+		       COALESCE(p.country, '') AS "publisher.country", COALESCE(p.website, '') AS "publisher.website"
+		FROM books b
+		JOIN authors a ON b.author_id = a.id
+		LEFT JOIN publishers p ON b.publisher_id = p.id`
+
+var (
+	ErrBookNotFound    = errors.New("book not found")
+	ErrBookSold        = errors.New("book was sold, it can't be deleted")
+	ErrUnknownBookSort = errors.New("unknown book sort key")
+)
+
+// bookReferenceError tells which reference of a book doesn't exist when the insert or update
+// breaks a foreign key, it returns err unchanged otherwise.
+func bookReferenceError(err error) error {
+	switch {
+	case isForeignKeyViolation(err, "books_author_id_fkey"):
+		return ErrAuthorNotFound
+	case isForeignKeyViolation(err, "books_publisher_id_fkey"):
+		return ErrPublisherNotFound
+	}
+	return err
+}
+
+// bookSortOrders maps the accepted sort keys to their ORDER BY clause, no key sorts by title.
+var bookSortOrders = map[string]string{
+	"title":  "b.title",
+	"rating": "b.rating_average DESC, b.rating_count DESC, b.title",
+	"price":  "b.price, b.title",
+}
+
+// BookSortKeys returns the accepted sort keys, sorted.
+func BookSortKeys() []string {
+	keys := make([]string, 0, len(bookSortOrders))
+	for key := range bookSortOrders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 type BookRepository struct {
 	dbTemplate *DBTemplate
 }
 
 func NewBookRepository(dbTemplate *DBTemplate) *BookRepository {
 	return &BookRepository{
-		dbTemplate: dbTemplate,
+		dbTemplate: dbTemplate.Repository("book"),
 	}
 }
 
 func (repo *BookRepository) Create(book Book) (Book, error) {
 	book.TextGenres = strings.Join(book.Genres, ",")
+	if book.TaxCategory == "" {
+		book.TaxCategory = DefaultTaxCategory
+	}
 	query := `
-		INSERT INTO books (title, author_id, genres, published_at, price, stock)
-		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-	id, err := ExecuteInsert(repo.dbTemplate, query, book.Title, book.Author.ID, book.TextGenres, book.PublishedAt, book.Price, book.Stock)
+		INSERT INTO books (title, author_id, publisher_id, genres, published_at, price, stock, tax_category, weight_grams)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6, $7, $8, $9) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, book.Title, book.Author.ID, book.Publisher.ID, book.TextGenres, book.PublishedAt, book.Price, book.Stock, book.TaxCategory,
+		book.WeightGrams)
 	if err != nil {
-		return Book{}, err
+		return Book{}, bookReferenceError(err)
 	}
 	book.ID = id
 	return book, nil
 }
 
 func (repo *BookRepository) GetById(id int) (Book, error) {
-	query := `
-		SELECT b.id, b.title, b.genres as "genres", b.published_at, b.price, b.stock,
-		       a.id AS "author.id", a.first_name AS "author.first_name", a.last_name AS "author.last_name", a.bio AS "author.bio"
-		FROM books b
-		JOIN authors a ON b.author_id = a.id
+	query := bookSelectQuery + `
 		WHERE b.id = $1`
 	book, err := QueryStruct[Book](repo.dbTemplate, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Book{}, errors.New("book not found")
+			return Book{}, ErrBookNotFound
 		}
 		return Book{}, err
 	}
@@ -49,12 +94,17 @@ func (repo *BookRepository) GetById(id int) (Book, error) {
 
 func (repo *BookRepository) Update(id int, updated Book) (Book, error) {
 	updated.TextGenres = strings.Join(updated.Genres, ",")
+	if updated.TaxCategory == "" {
+		updated.TaxCategory = DefaultTaxCategory
+	}
 	query := `
-		UPDATE books SET title = $1, author_id = $2, genres = $3, published_at = $4, price = $5, stock = $6
-		WHERE id = $7`
-	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, updated.Title, updated.Author.ID, updated.TextGenres, updated.PublishedAt, updated.Price, updated.Stock, id)
+		UPDATE books SET title = $1, author_id = $2, publisher_id = NULLIF($3, 0), genres = $4, published_at = $5, price = $6, stock = $7,
+		                 tax_category = $8, weight_grams = $9
+		WHERE id = $10`
+	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, updated.Title, updated.Author.ID, updated.Publisher.ID, updated.TextGenres, updated.PublishedAt, updated.Price, updated.Stock,
+		updated.TaxCategory, updated.WeightGrams, id)
 	if err != nil {
-		return Book{}, err
+		return Book{}, bookReferenceError(err)
 	}
 	updated.ID = id
 	return updated, nil
@@ -64,20 +114,19 @@ func (repo *BookRepository) Delete(id int) error {
 	query := `DELETE FROM books WHERE id = $1`
 	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, id)
 	if err != nil {
+		if isForeignKeyViolation(err, "order_items_book_id_fkey") || isForeignKeyViolation(err, "refunds_book_id_fkey") {
+			return ErrBookSold
+		}
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("book not found")
+		return ErrBookNotFound
 	}
 	return nil
 }
 
 func (repo *BookRepository) GetAll() ([]Book, error) {
-	query := `
-		SELECT b.id AS id, b.title, b.genres, b.published_at, b.price, b.stock,
-		       a.id AS "author.id", a.first_name AS "author.first_name", a.last_name AS "author.last_name", a.bio AS "author.bio"
-		FROM books b
-		JOIN authors a ON b.author_id = a.id`
+	query := bookSelectQuery
 	books, err := QueryStructs[Book](repo.dbTemplate, query)
 	if err != nil {
 		return nil, err
@@ -90,21 +139,26 @@ func (repo *BookRepository) GetAll() ([]Book, error) {
 
 
 func (repo *BookRepository) GetBookBySearchCriteria(s SearchCriteria) ([]Book, error) {
-	query := `
-		SELECT b.id AS id, b.title, b.genres, b.published_at, b.price, b.stock,
-			a.id AS "author.id", a.first_name AS "author.first_name", a.last_name AS "author.last_name", a.bio AS "author.bio"
-		FROM books b
-		JOIN authors a ON b.author_id = a.id
+	query := bookSelectQuery + `
 		WHERE ($1 = '' OR b.title ILIKE $1)
 		AND ($2 = '' OR a.first_name ILIKE $2)
 		AND ($3 = '' OR b.genres ILIKE $3)
-		ORDER BY b.title
+		AND ($4 = '' OR p.name ILIKE $4)
 	`
+	if s.SortBy == "" {
+		s.SortBy = "title"
+	}
+	order, ok := bookSortOrders[s.SortBy]
+	if !ok {
+		return nil, ErrUnknownBookSort
+	}
+	query += "ORDER BY " + order
 
 	books, err := QueryStructs[Book](repo.dbTemplate, query,
 		s.Title,
 		s.AuthorName, 
-		"%" + s.Genre + "%") 
+		"%" + s.Genre + "%",
+		s.Publisher) 
 
 
 	if err != nil {
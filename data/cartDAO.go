@@ -0,0 +1,195 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// CartInactivityTimeout is how long a cart survives without being modified.
+const CartInactivityTimeout = 24 * time.Hour
+
+var ErrCartItemNotFound = errors.New("book is not in the cart")
+
+type CartRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewCartRepository(dbTemplate *DBTemplate) *CartRepository {
+	return &CartRepository{
+		dbTemplate: dbTemplate.Repository("cart"),
+	}
+}
+
+type cartRow struct {
+	ID        int       `db:"id"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Get prices the cart of a session against the current book prices and stock.
+// Sessions without a cart, or whose cart expired, get an empty cart.
+func (repo *CartRepository) Get(sessionKey string) (Cart, error) {
+	row, err := QueryStruct[cartRow](repo.dbTemplate, `
+		SELECT id, updated_at FROM carts
+		WHERE session_key = $1 AND updated_at > $2`, sessionKey, time.Now().UTC().Add(-CartInactivityTimeout))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Cart{Lines: []CartLine{}, OutOfStockBookIDs: []int{}}, nil
+		}
+		return Cart{}, err
+	}
+
+	query := `
+		SELECT ci.quantity,
+		       b.id AS "book.id", b.title AS "book.title", b.price AS "book.price", b.stock AS "book.stock",
+		       a.id AS "book.author.id", a.first_name AS "book.author.first_name", a.last_name AS "book.author.last_name"
+		FROM cart_items ci
+		JOIN books b ON ci.book_id = b.id
+		JOIN authors a ON b.author_id = a.id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.added_at, b.id`
+	lines, err := QueryStructs[CartLine](repo.dbTemplate, query, row.ID)
+	if err != nil {
+		return Cart{}, err
+	}
+
+	cart := Cart{
+		Lines:             []CartLine{},
+		OutOfStockBookIDs: []int{},
+		UpdatedAt:         row.UpdatedAt,
+		ExpiresAt:         row.UpdatedAt.Add(CartInactivityTimeout),
+	}
+	for _, line := range lines {
+		line.UnitPrice = line.Book.Price
+		line.LineTotal = roundMoney(line.UnitPrice * float64(line.Quantity))
+		line.InStock = line.Book.Stock >= line.Quantity
+		if !line.InStock {
+			cart.OutOfStockBookIDs = append(cart.OutOfStockBookIDs, line.Book.ID)
+		}
+		cart.ItemCount += line.Quantity
+		cart.Subtotal += line.LineTotal
+		cart.Lines = append(cart.Lines, line)
+	}
+	cart.Subtotal = roundMoney(cart.Subtotal)
+	return cart, nil
+}
+
+// touchCart returns the id of the session cart, creating it when needed, and marks it as active.
+// An expired cart that the janitor did not sweep yet is emptied first.
+func touchCart(tx *DBTemplate, sessionKey string) (int, error) {
+	now := time.Now().UTC()
+	row, err := QueryStruct[cartRow](tx, `SELECT id, updated_at FROM carts WHERE session_key = $1 FOR UPDATE`, sessionKey)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+		return ExecuteInsert(tx, `
+			INSERT INTO carts (session_key, created_at, updated_at)
+			VALUES ($1, $2, $2)
+			ON CONFLICT (session_key) DO UPDATE SET updated_at = EXCLUDED.updated_at
+			RETURNING id`, sessionKey, now)
+	}
+
+	if row.UpdatedAt.Before(now.Add(-CartInactivityTimeout)) {
+		if _, err := ExecuteUpdateOrDelete(tx, `DELETE FROM cart_items WHERE cart_id = $1`, row.ID); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := ExecuteUpdateOrDelete(tx, `UPDATE carts SET updated_at = $1 WHERE id = $2`, now, row.ID); err != nil {
+		return 0, err
+	}
+	return row.ID, nil
+}
+
+func (repo *CartRepository) AddItem(sessionKey string, bookID int, quantity int) error {
+	return InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		exists, err := QueryStruct[bool](tx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)`, bookID)
+		if err != nil {
+			return err
+		}
+		if !*exists {
+			return ErrBookNotFound
+		}
+
+		cartID, err := touchCart(tx, sessionKey)
+		if err != nil {
+			return err
+		}
+		_, err = ExecuteUpdateOrDelete(tx, `
+			INSERT INTO cart_items (cart_id, book_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (cart_id, book_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`,
+			cartID, bookID, quantity)
+		return err
+	})
+}
+
+// UpdateQuantity sets the quantity of a book already in the cart, zero removes it.
+func (repo *CartRepository) UpdateQuantity(sessionKey string, bookID int, quantity int) error {
+	if quantity == 0 {
+		return repo.RemoveItem(sessionKey, bookID)
+	}
+	return InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		cartID, err := touchCart(tx, sessionKey)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := ExecuteUpdateOrDelete(tx, `
+			UPDATE cart_items SET quantity = $1
+			WHERE cart_id = $2 AND book_id = $3`, quantity, cartID, bookID)
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrCartItemNotFound
+		}
+		return nil
+	})
+}
+
+func (repo *CartRepository) RemoveItem(sessionKey string, bookID int) error {
+	return InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		cartID, err := touchCart(tx, sessionKey)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := ExecuteUpdateOrDelete(tx, `
+			DELETE FROM cart_items WHERE cart_id = $1 AND book_id = $2`, cartID, bookID)
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrCartItemNotFound
+		}
+		return nil
+	})
+}
+
+func (repo *CartRepository) Clear(sessionKey string) error {
+	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, `DELETE FROM carts WHERE session_key = $1`, sessionKey)
+	return err
+}
+
+func (repo *CartRepository) DeleteExpired() (int, error) {
+	return ExecuteUpdateOrDelete(repo.dbTemplate, `DELETE FROM carts WHERE updated_at <= $1`, time.Now().UTC().Add(-CartInactivityTimeout))
+}
+
+// StartCartJanitor periodically removes the carts that have been inactive for longer than CartInactivityTimeout.
+func StartCartJanitor(store *DBTemplate) {
+	repo := NewCartRepository(store)
+
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		removed, err := repo.DeleteExpired()
+		if err != nil {
+			log.Println("Error removing expired carts:", err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("Removed %d expired carts", removed)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrEmptyCheckout    = errors.New("nothing to check out")
+	ErrCustomerNotFound = errors.New("customer not found")
+)
+
+// InsufficientStockError lists the books that cannot be supplied in the requested quantity.
+type InsufficientStockError struct {
+	BookIDs []int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for books %v", e.BookIDs)
+}
+
+// customerAddressQuery loads a customer with the address orders are taxed, shipped and invoiced to.
+const customerAddressQuery = `
+		SELECT c.id, c.name, c.email, c.created_at,
+		       COALESCE(a.street, '') AS "address.street", COALESCE(a.city, '') AS "address.city",
+		       COALESCE(a.state, '') AS "address.state", COALESCE(a.postal_code, '') AS "address.postal_code",
+		       COALESCE(a.country, '') AS "address.country"
+		FROM customers c
+		LEFT JOIN addresses a ON c.address_id = a.id
+		WHERE c.id = $1`
+
+type stockedBook struct {
+	ID          int     `db:"id"`
+	Title       string  `db:"title"`
+	AuthorID    int     `db:"author_id"`
+	Genres      string  `db:"genres"`
+	Price       float64 `db:"price"`
+	Stock       int     `db:"stock"`
+	TaxCategory string  `db:"tax_category"`
+	WeightGrams int     `db:"weight_grams"`
+}
+
+// priceOrder computes the line totals and the order totals from the unit prices, discounts and taxes of the items.
+// The total is always derived server side, clients never send it.
+func priceOrder(order *Order) {
+	subtotal, discount, tax := 0.0, 0.0, 0.0
+	for i, item := range order.Items {
+		amount := roundMoney(item.UnitPrice * float64(item.Quantity))
+		order.Items[i].LineTotal = roundMoney(amount - item.DiscountAmount)
+		order.Items[i].GrossTotal = roundMoney(order.Items[i].LineTotal + item.TaxAmount)
+		subtotal += amount
+		discount += item.DiscountAmount
+		tax += item.TaxAmount
+	}
+	order.Subtotal = roundMoney(subtotal)
+	order.DiscountTotal = roundMoney(discount)
+	order.TaxTotal = roundMoney(tax)
+	order.NetTotal = roundMoney(order.Subtotal - order.DiscountTotal + order.ShippingTotal)
+	order.TotalPrice = roundMoney(order.NetTotal + order.TaxTotal)
+	order.TaxBreakdown = taxBreakdown(order.Items)
+}
+
+func cartCheckoutItems(tx *DBTemplate, sessionKey string) ([]CheckoutItem, error) {
+	query := `
+		SELECT ci.book_id, ci.quantity
+		FROM cart_items ci
+		JOIN carts c ON ci.cart_id = c.id
+		WHERE c.session_key = $1 AND c.updated_at > $2
+		ORDER BY ci.added_at, ci.book_id`
+	return QueryStructs[CheckoutItem](tx, query, sessionKey, time.Now().UTC().Add(-CartInactivityTimeout))
+}
+
+// PlaceOrder turns the requested items, or the session cart when none are given, into a pending order.
+// Stock is checked and decremented, and the order and its items are created, in a single transaction.
+func (repo *OrderRepository) PlaceOrder(request CheckoutRequest) (Order, error) {
+	var order Order
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		items := request.Items
+		fromCart := len(items) == 0 && request.SessionKey != ""
+		if fromCart {
+			var err error
+			if items, err = cartCheckoutItems(tx, request.SessionKey); err != nil {
+				return err
+			}
+		}
+
+		// The same book may be listed more than once, quantities are summed.
+		quantities := make(map[int]int)
+		var bookIDs []int
+		for _, item := range items {
+			if _, exists := quantities[item.BookID]; !exists {
+				bookIDs = append(bookIDs, item.BookID)
+			}
+			quantities[item.BookID] += item.Quantity
+		}
+		if len(bookIDs) == 0 {
+			return ErrEmptyCheckout
+		}
+
+		customer, err := QueryStruct[Customer](tx, customerAddressQuery, request.CustomerID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrCustomerNotFound
+			}
+			return err
+		}
+
+		// Rows are locked in id order so concurrent checkouts cannot deadlock each other.
+		books, err := QueryStructs[stockedBook](tx, `
+			SELECT id, title, author_id, genres, price, stock, tax_category, weight_grams FROM books
+			WHERE id = ANY($1)
+			ORDER BY id
+			FOR UPDATE`, pq.Array(bookIDs))
+		if err != nil {
+			return err
+		}
+		booksByID := make(map[int]stockedBook, len(books))
+		for _, book := range books {
+			booksByID[book.ID] = book
+		}
+
+		order = Order{
+			Customer:  *customer,
+			CreatedAt: time.Now().UTC(),
+			Status:    OrderPending,
+		}
+		var outOfStock []int
+		for _, bookID := range bookIDs {
+			book, exists := booksByID[bookID]
+			if !exists {
+				return fmt.Errorf("%w: %d", ErrBookNotFound, bookID)
+			}
+			if book.Stock < quantities[bookID] {
+				outOfStock = append(outOfStock, bookID)
+				continue
+			}
+			order.Items = append(order.Items, OrderItem{
+				Book: Book{
+					ID:          book.ID,
+					Title:       book.Title,
+					Author:      Author{ID: book.AuthorID},
+					Genres:      strings.Split(book.Genres, ","),
+					Price:       book.Price,
+					TaxCategory: book.TaxCategory,
+					WeightGrams: book.WeightGrams,
+				},
+				Quantity:  quantities[bookID],
+				UnitPrice: book.Price,
+			})
+		}
+		if len(outOfStock) > 0 {
+			return &InsufficientStockError{BookIDs: outOfStock}
+		}
+
+		promotions, usage, err := loadPromotions(tx, request.PromotionCodes, customer.ID, order.Items, order.CreatedAt)
+		if err != nil {
+			return err
+		}
+		order.Promotions, order.SkippedPromotions = applyPromotions(&order, promotions, usage, order.CreatedAt)
+
+		rules, err := loadTaxRules(tx, customer.Address.Country)
+		if err != nil {
+			return err
+		}
+		applyTaxes(&order, rules)
+		if err := applyShipping(tx, &order); err != nil {
+			return err
+		}
+		priceOrder(&order)
+
+		created, err := NewOrderRepository(tx).Create(order)
+		if err != nil {
+			return err
+		}
+		order = created
+		if err := recordStatusChange(tx, order.ID, "", OrderPending, "", order.CreatedAt); err != nil {
+			return err
+		}
+		order.StatusHistory = []OrderStatusChange{{To: OrderPending, ChangedAt: order.CreatedAt}}
+
+		for i, item := range order.Items {
+			id, err := ExecuteInsert(tx, `
+				INSERT INTO order_items (order_id, book_id, quantity, unit_price, discount_amount, line_total, tax_rate, tax_amount)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+				order.ID, item.Book.ID, item.Quantity, item.UnitPrice, item.DiscountAmount, item.LineTotal, item.TaxRate, item.TaxAmount)
+			if err != nil {
+				return err
+			}
+			order.Items[i].ID = id
+
+			if _, err := ExecuteUpdateOrDelete(tx, `UPDATE books SET stock = stock - $1 WHERE id = $2`, item.Quantity, item.Book.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := recordRedemptions(tx, order); err != nil {
+			return err
+		}
+
+		if fromCart {
+			if _, err := ExecuteUpdateOrDelete(tx, `DELETE FROM carts WHERE session_key = $1`, request.SessionKey); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
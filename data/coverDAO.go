@@ -0,0 +1,55 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrCoverNotFound = errors.New("cover not found")
+
+type CoverRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewCoverRepository(dbTemplate *DBTemplate) *CoverRepository {
+	return &CoverRepository{
+		dbTemplate: dbTemplate.Repository("cover"),
+	}
+}
+
+// Save inserts or replaces the cover metadata of a book and reports whether a cover already existed.
+func (repo *CoverRepository) Save(cover BookCover) (bool, error) {
+	query := `
+		INSERT INTO book_covers (book_id, content_type, size_bytes, width, height, etag,
+		                         thumbnail_content_type, thumbnail_size_bytes, thumbnail_etag, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (book_id) DO UPDATE SET
+			content_type = EXCLUDED.content_type, size_bytes = EXCLUDED.size_bytes,
+			width = EXCLUDED.width, height = EXCLUDED.height, etag = EXCLUDED.etag,
+			thumbnail_content_type = EXCLUDED.thumbnail_content_type,
+			thumbnail_size_bytes = EXCLUDED.thumbnail_size_bytes,
+			thumbnail_etag = EXCLUDED.thumbnail_etag, updated_at = EXCLUDED.updated_at
+		RETURNING (xmax <> 0)`
+	replaced, err := QueryStruct[bool](repo.dbTemplate, query, cover.BookID, cover.ContentType, cover.Size, cover.Width, cover.Height, cover.ETag,
+		cover.ThumbnailContentType, cover.ThumbnailSize, cover.ThumbnailETag, cover.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return *replaced, nil
+}
+
+func (repo *CoverRepository) GetByBookId(bookID int) (BookCover, error) {
+	query := `
+		SELECT book_id, content_type, size_bytes, width, height, etag,
+		       thumbnail_content_type, thumbnail_size_bytes, thumbnail_etag, updated_at
+		FROM book_covers
+		WHERE book_id = $1`
+	cover, err := QueryStruct[BookCover](repo.dbTemplate, query, bookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return BookCover{}, ErrCoverNotFound
+		}
+		return BookCover{}, err
+	}
+	return *cover, nil
+}
@@ -1,57 +1,95 @@
 package data
 
 import (
-	"errors"
 	"database/sql"
+	"errors"
+	"time"
 )
 
+var ErrCustomerEmailTaken = errors.New("email is already used by another customer")
+
 type CustomerRepository struct {
 	dbTemplate *DBTemplate
 }
 
 func NewCustomerRepository(dbTemplate *DBTemplate) *CustomerRepository {
 	return &CustomerRepository{
-		dbTemplate: dbTemplate,
+		dbTemplate: dbTemplate.Repository("customer"),
 	}
 }
 
-func (repo *CustomerRepository) Create(customer Customer) (Customer, error) {
+// saveAddress returns the id of the address, creating it when no customer uses it yet.
+// Addresses are unique and shared between the customers living there.
+func saveAddress(tx *DBTemplate, address Address) (int, error) {
 	query := `
-		INSERT INTO customers (name, email, address, created_at)
-		VALUES ($1, $2, $3, $4) RETURNING id`
-	id, err := ExecuteInsert(repo.dbTemplate, query, customer.Name, customer.Email, customer.Address, customer.CreatedAt)
+		INSERT INTO addresses (street, city, state, postal_code, country)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (street, city, state, postal_code, country) DO UPDATE SET street = EXCLUDED.street
+		RETURNING id`
+	return ExecuteInsert(tx, query, address.Street, address.City, address.State, address.PostalCode, address.Country)
+}
+
+func (repo *CustomerRepository) Create(customer Customer) (Customer, error) {
+	customer.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		addressID, err := saveAddress(tx, customer.Address)
+		if err != nil {
+			return err
+		}
+		query := `
+			INSERT INTO customers (name, email, address_id, created_at)
+			VALUES ($1, $2, $3, $4) RETURNING id`
+		customer.ID, err = ExecuteInsert(tx, query, customer.Name, customer.Email, addressID, customer.CreatedAt)
+		return err
+	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			return Customer{}, ErrCustomerEmailTaken
+		}
 		return Customer{}, err
 	}
-	customer.ID = id
 	return customer, nil
 }
 
 func (repo *CustomerRepository) GetById(id int) (Customer, error) {
-	query := `
-		SELECT id, name, email, address, created_at
-		FROM customers
-		WHERE id = $1`
-	customer, err := QueryStruct[Customer](repo.dbTemplate, query, id)
+	customer, err := QueryStruct[Customer](repo.dbTemplate, customerAddressQuery, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Customer{}, errors.New("customer not found")
+			return Customer{}, ErrCustomerNotFound
 		}
 		return Customer{}, err
 	}
 	return *customer, nil
 }
 
+// Update replaces the name, email and address of a customer, its creation time is kept.
 func (repo *CustomerRepository) Update(id int, updated Customer) (Customer, error) {
-	query := `
-		UPDATE customers SET name = $1, email = $2, address = $3, created_at = $4
-		WHERE id = $5`
-	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, updated.Name, updated.Email, updated.Address, updated.CreatedAt, id)
+	var customer Customer
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		addressID, err := saveAddress(tx, updated.Address)
+		if err != nil {
+			return err
+		}
+		query := `
+			UPDATE customers SET name = $1, email = $2, address_id = $3
+			WHERE id = $4`
+		rowsAffected, err := ExecuteUpdateOrDelete(tx, query, updated.Name, updated.Email, addressID, id)
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrCustomerNotFound
+		}
+		customer, err = NewCustomerRepository(tx).GetById(id)
+		return err
+	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			return Customer{}, ErrCustomerEmailTaken
+		}
 		return Customer{}, err
 	}
-	updated.ID = id
-	return updated, nil
+	return customer, nil
 }
 
 func (repo *CustomerRepository) Delete(id int) error {
@@ -61,15 +99,20 @@ func (repo *CustomerRepository) Delete(id int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("customer not found")
+		return ErrCustomerNotFound
 	}
 	return nil
 }
 
 func (repo *CustomerRepository) GetAll() ([]Customer, error) {
 	query := `
-		SELECT id, name, email, address, created_at
-		FROM customers`
+		SELECT c.id, c.name, c.email, c.created_at,
+		       COALESCE(a.street, '') AS "address.street", COALESCE(a.city, '') AS "address.city",
+		       COALESCE(a.state, '') AS "address.state", COALESCE(a.postal_code, '') AS "address.postal_code",
+		       COALESCE(a.country, '') AS "address.country"
+		FROM customers c
+		LEFT JOIN addresses a ON c.address_id = a.id
+		ORDER BY c.id`
 	customers, err := QueryStructs[Customer](repo.dbTemplate, query)
 	if err != nil {
 		return nil, err
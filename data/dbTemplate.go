@@ -1,13 +1,49 @@
 package data
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"finalproject/logging"
+	"finalproject/metrics"
+	"finalproject/tracing"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// queryer is implemented by both *sqlx.DB and *sqlx.Tx, so the helpers below work
+// the same way inside and outside of a transaction.
+type queryer interface {
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+var (
+	queryDuration = metrics.NewHistogramVec("db_query_duration_seconds", "Duration of the database queries by repository and operation.", metrics.DefaultBuckets, "repository", "operation")
+	queryErrors   = metrics.NewCounterVec("db_query_errors_total", "Failed database queries by repository and operation.", "repository", "operation")
+
+	tracer = tracing.Tracer("data")
+)
+
+// DBTemplate runs the queries of the repositories. A template bound to a request context with
+// WithContext cancels its queries with the request and logs with the request logger.
 type DBTemplate struct {
-	db *sqlx.DB
+	db   queryer
+	pool *sqlx.DB
+	ctx  context.Context
+	// repository labels the query metrics of the repository the template was given to.
+	repository string
 }
 
 func NewDBTemplate(connStr string) *DBTemplate {
@@ -15,12 +51,132 @@ func NewDBTemplate(connStr string) *DBTemplate {
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	return &DBTemplate{db: db}
+	// sqlx.Open only checks the connection string, the first connection is made here. An unreachable
+	// database isn't fatal: the server starts unready and /readyz reports it.
+	template := &DBTemplate{db: db, pool: db, ctx: context.Background()}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := template.WithContext(ctx).Ping(); err != nil {
+		log.Printf("Database unreachable at startup: %v", err)
+	}
+	return template
+}
+
+// WithContext returns a template running its queries in ctx.
+func (template *DBTemplate) WithContext(ctx context.Context) *DBTemplate {
+	return &DBTemplate{db: template.db, pool: template.pool, ctx: ctx, repository: template.repository}
+}
+
+// Repository returns a template whose queries are measured under the given repository name.
+func (template *DBTemplate) Repository(name string) *DBTemplate {
+	return &DBTemplate{db: template.db, pool: template.pool, ctx: template.ctx, repository: name}
+}
+
+// Stats returns the statistics of the connection pool.
+func (template *DBTemplate) Stats() sql.DBStats {
+	return template.pool.Stats()
+}
+
+func (template *DBTemplate) context() context.Context {
+	if template.ctx == nil {
+		return context.Background()
+	}
+	return template.ctx
+}
+
+// Logger returns the logger of the request the template is bound to, or the default logger.
+func (template *DBTemplate) Logger() *slog.Logger {
+	return logging.FromContext(template.context())
+}
+
+func (template *DBTemplate) repositoryName() string {
+	if template.repository == "" {
+		return "unknown"
+	}
+	return template.repository
+}
+
+// startQuery opens the span of a query, a child of the request or transaction span, with the
+// sanitized SQL. The query runs in the returned context; finish ends the span and records the
+// duration and failure of the query. sql.ErrNoRows is a lookup that found nothing, not an error.
+func (template *DBTemplate) startQuery(operation string, query string) (context.Context, func(err error)) {
+	start := time.Now()
+	repository := template.repositoryName()
+	ctx, span := tracer.Start(template.context(), repository+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(tracing.SanitizeSQL(query)),
+			attribute.String("db.repository", repository),
+		),
+	)
+	return ctx, func(err error) {
+		failed := err != nil && !errors.Is(err, sql.ErrNoRows)
+		if failed {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		queryDuration.With(repository, operation).Observe(time.Since(start).Seconds())
+		if failed {
+			queryErrors.With(repository, operation).Inc()
+		}
+		template.logQuery(query, start, err)
+	}
+}
+
+// logQuery logs a query at debug level, with its whitespace collapsed.
+func (template *DBTemplate) logQuery(query string, start time.Time, err error) {
+	logger := template.Logger()
+	if !logger.Enabled(template.context(), slog.LevelDebug) {
+		return
+	}
+	attrs := []any{"query", strings.Join(strings.Fields(query), " "), "duration_ms", float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		attrs = append(attrs, "error", err.Error())
+	}
+	if template.repository != "" {
+		attrs = append(attrs, "repository", template.repository)
+	}
+	logger.Debug("query", attrs...)
+}
+
+// InTransaction runs fn with a template bound to a new transaction. The transaction is
+// committed when fn returns nil and rolled back otherwise. When template is already
+// bound to a transaction, fn joins it instead of starting a new one.
+func InTransaction(template *DBTemplate, fn func(tx *DBTemplate) error) error {
+	if _, ok := template.db.(*sqlx.Tx); ok {
+		return fn(template)
+	}
+
+	ctx, span := tracer.Start(template.context(), template.repositoryName()+" transaction")
+	defer span.End()
+
+	tx, err := template.pool.BeginTxx(ctx, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := fn(&DBTemplate{db: tx, pool: template.pool, ctx: ctx, repository: template.repository}); err != nil {
+		tx.Rollback()
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
 }
 
 func QueryStructs[T any](template *DBTemplate, query string, args ...any) ([]T, error) {
 	var results []T
-	if err := template.db.Select(&results, query, args...); err != nil {
+	ctx, finish := template.startQuery("select", query)
+	err := template.db.SelectContext(ctx, &results, query, args...)
+	finish(err)
+	if err != nil {
 		return nil, err
 	}
 	return results, nil
@@ -28,7 +184,10 @@ func QueryStructs[T any](template *DBTemplate, query string, args ...any) ([]T,
 
 func QueryStruct[T any](template *DBTemplate, query string, args ...any) (*T, error) {
 	var result T
-	if err := template.db.Get(&result, query, args...); err != nil {
+	ctx, finish := template.startQuery("get", query)
+	err := template.db.GetContext(ctx, &result, query, args...)
+	finish(err)
+	if err != nil {
 		return nil, err
 	}
 	return &result, nil
@@ -36,7 +195,9 @@ func QueryStruct[T any](template *DBTemplate, query string, args ...any) (*T, er
 
 func ExecuteInsert(template *DBTemplate, query string, args ...any) (int, error) {
 	var id int
-	err := template.db.QueryRow(query, args...).Scan(&id)
+	ctx, finish := template.startQuery("insert", query)
+	err := template.db.QueryRowContext(ctx, query, args...).Scan(&id)
+	finish(err)
 	if err != nil {
 		return 0, err
 	}
@@ -45,7 +206,9 @@ func ExecuteInsert(template *DBTemplate, query string, args ...any) (int, error)
 
 
 func ExecuteUpdateOrDelete(template *DBTemplate, query string, args ...any) (int, error) {
-	result, err := template.db.Exec(query, args...)
+	ctx, finish := template.startQuery("exec", query)
+	result, err := template.db.ExecContext(ctx, query, args...)
+	finish(err)
 	if err != nil {
 		return 0, err
 	}
@@ -55,3 +218,20 @@ func ExecuteUpdateOrDelete(template *DBTemplate, query string, args ...any) (int
 	}
 	return int(affectedRows), nil
 }
+
+// SchemaVersion is the version of sql/DDL.sql this code expects in the schema_version table.
+const SchemaVersion = 2
+
+// GetSchemaVersion returns the version recorded in the schema_version table of the database.
+func (template *DBTemplate) GetSchemaVersion() (int, error) {
+	version, err := QueryStruct[int](template.Repository("schema"), `SELECT MAX(version) FROM schema_version`)
+	if err != nil {
+		return 0, err
+	}
+	return *version, nil
+}
+
+// Ping checks that the database can be reached.
+func (template *DBTemplate) Ping() error {
+	return template.pool.PingContext(template.context())
+}
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// InvoiceSeller is printed as the issuer on every invoice.
+var InvoiceSeller = InvoiceParty{
+	Name: "Online Bookstore",
+	Address: Address{
+		Street:     "1 Library Street",
+		City:       "Stockholm",
+		PostalCode: "111 52",
+		Country:    "Sweden",
+	},
+}
+
+// Orders are invoiced once they are paid, refunded orders keep the invoice issued before.
+var invoiceableOrderStatuses = []string{OrderPaid, OrderShipped, OrderDelivered, OrderRefunded}
+
+type InvoiceNotAvailableError struct {
+	Status string
+}
+
+func (e *InvoiceNotAvailableError) Error() string {
+	return fmt.Sprintf("no invoice can be issued for a %s order", e.Status)
+}
+
+type InvoiceRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewInvoiceRepository(dbTemplate *DBTemplate) *InvoiceRepository {
+	return &InvoiceRepository{
+		dbTemplate: dbTemplate.Repository("invoice"),
+	}
+}
+
+func getInvoiceSnapshot(template *DBTemplate, query string, args ...any) (Invoice, error) {
+	snapshot, err := QueryStruct[[]byte](template, query, args...)
+	if err != nil {
+		return Invoice{}, err
+	}
+	var invoice Invoice
+	if err := json.Unmarshal(*snapshot, &invoice); err != nil {
+		return Invoice{}, err
+	}
+	return invoice, nil
+}
+
+// nextInvoiceNumber takes the next number of the year. The counter row stays locked until the
+// transaction ends, so two invoices never share a number and a rolled back one leaves no gap.
+func nextInvoiceNumber(tx *DBTemplate, issuedAt time.Time) (string, error) {
+	number, err := QueryStruct[int](tx, `
+		INSERT INTO invoice_counters (year, last_number) VALUES ($1, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+		RETURNING last_number`, issuedAt.Year())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("INV-%d-%06d", issuedAt.Year(), *number), nil
+}
+
+func newInvoice(order Order, customer Customer, number string, issuedAt time.Time) Invoice {
+	invoice := Invoice{
+		Number:    number,
+		OrderID:   order.ID,
+		IssuedAt:  issuedAt,
+		OrderedAt: order.CreatedAt,
+		Seller:    InvoiceSeller,
+		Customer: InvoiceParty{
+			Name:    customer.Name,
+			Email:   customer.Email,
+			Address: customer.Address,
+		},
+		TaxBreakdown:  order.TaxBreakdown,
+		Subtotal:      order.Subtotal,
+		DiscountTotal: order.DiscountTotal,
+		ShippingTotal: order.ShippingTotal,
+		NetTotal:      order.NetTotal,
+		TaxTotal:      order.TaxTotal,
+		TotalPrice:    order.TotalPrice,
+	}
+	for _, item := range order.Items {
+		invoice.Lines = append(invoice.Lines, InvoiceLine{
+			Description:    item.Book.Title,
+			Quantity:       item.Quantity,
+			UnitPrice:      item.UnitPrice,
+			DiscountAmount: item.DiscountAmount,
+			NetAmount:      item.LineTotal,
+			TaxRate:        item.TaxRate,
+			TaxAmount:      item.TaxAmount,
+			GrossAmount:    item.GrossTotal,
+		})
+	}
+	return invoice
+}
+
+// IssueForOrder returns the invoice of an order, issuing it on the first call.
+func (repo *InvoiceRepository) IssueForOrder(orderID int) (Invoice, error) {
+	var invoice Invoice
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		// The order lock makes concurrent first requests wait for each other instead of both issuing.
+		status, err := QueryStruct[string](tx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+
+		invoice, err = getInvoiceSnapshot(tx, `SELECT snapshot FROM invoices WHERE order_id = $1`, orderID)
+		if err == nil || !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if !slices.Contains(invoiceableOrderStatuses, *status) {
+			return &InvoiceNotAvailableError{Status: *status}
+		}
+
+		order, err := NewOrderRepository(tx).GetById(orderID)
+		if err != nil {
+			return err
+		}
+		customer, err := QueryStruct[Customer](tx, customerAddressQuery, order.Customer.ID)
+		if err != nil {
+			return err
+		}
+
+		issuedAt := time.Now().UTC()
+		number, err := nextInvoiceNumber(tx, issuedAt)
+		if err != nil {
+			return err
+		}
+		invoice = newInvoice(order, *customer, number, issuedAt)
+		snapshot, err := json.Marshal(invoice)
+		if err != nil {
+			return err
+		}
+		_, err = ExecuteInsert(tx, `
+			INSERT INTO invoices (number, order_id, issued_at, snapshot)
+			VALUES ($1, $2, $3, $4) RETURNING id`, invoice.Number, orderID, issuedAt, snapshot)
+		return err
+	})
+	if err != nil {
+		return Invoice{}, err
+	}
+	return invoice, nil
+}
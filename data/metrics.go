@@ -0,0 +1,61 @@
+package data
+
+import (
+	"context"
+	"finalproject/metrics"
+	"log"
+	"time"
+)
+
+// RegisterMetrics exposes the connection pool statistics of the template and the number of
+// active sessions, refresh tokens and API keys, counted on every scrape.
+func RegisterMetrics(template *DBTemplate, sessions SessionStore) {
+	metrics.NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.", func() float64 {
+		return float64(template.Stats().MaxOpenConnections)
+	})
+	metrics.NewGaugeFunc("db_open_connections", "Established connections, in use or idle.", func() float64 {
+		return float64(template.Stats().OpenConnections)
+	})
+	metrics.NewGaugeFunc("db_in_use_connections", "Connections in use.", func() float64 {
+		return float64(template.Stats().InUse)
+	})
+	metrics.NewGaugeFunc("db_idle_connections", "Idle connections.", func() float64 {
+		return float64(template.Stats().Idle)
+	})
+	metrics.NewCounterFunc("db_wait_count_total", "Connections waited for.", func() float64 {
+		return float64(template.Stats().WaitCount)
+	})
+	metrics.NewCounterFunc("db_wait_duration_seconds_total", "Time blocked waiting for a connection.", func() float64 {
+		return template.Stats().WaitDuration.Seconds()
+	})
+	metrics.NewCounterFunc("db_max_idle_closed_total", "Connections closed because of the maximum of idle connections.", func() float64 {
+		return float64(template.Stats().MaxIdleClosed)
+	})
+	metrics.NewCounterFunc("db_max_lifetime_closed_total", "Connections closed because of their maximum lifetime.", func() float64 {
+		return float64(template.Stats().MaxLifetimeClosed)
+	})
+
+	metrics.NewGaugeVecFunc("auth_active_tokens", "Active sessions, refresh tokens and API keys.", "kind", func() map[string]float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		bound := template.WithContext(ctx)
+
+		counts := map[string]float64{}
+		if count, err := sessions.CountActive(time.Now().UTC()); err != nil {
+			log.Println("Error counting active sessions:", err)
+		} else {
+			counts["session"] = float64(count)
+		}
+		if count, err := NewRefreshTokenRepository(bound).CountActive(); err != nil {
+			log.Println("Error counting active refresh tokens:", err)
+		} else {
+			counts["refresh_token"] = float64(count)
+		}
+		if count, err := NewAPIKeyRepository(bound).CountActive(); err != nil {
+			log.Println("Error counting active API keys:", err)
+		} else {
+			counts["api_key"] = float64(count)
+		}
+		return counts
+	})
+}
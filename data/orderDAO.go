@@ -5,21 +5,54 @@ import (
 	"database/sql"
 )
 
+const orderSelectQuery = `
+		SELECT o.id, o.subtotal, o.discount_total, o.tax_total, o.shipping_total, o.total_price - o.tax_total AS net_total,
+		       o.total_price, o.created_at, o.status,
+		       c.id AS "customer.id", c.name AS "customer.name", c.email AS "customer.email"
+		FROM orders o
+		JOIN customers c ON o.customer_id = c.id`
+
+const orderItemsQuery = `
+		SELECT oi.id, oi.quantity, oi.unit_price, oi.discount_amount, oi.line_total,
+		       oi.tax_rate, oi.tax_amount, oi.line_total + oi.tax_amount AS gross_total,
+		       b.id AS "book.id", b.title AS "book.title", b.price AS "book.price",
+		       COALESCE(p.id, 0) AS "book.publisher.id", COALESCE(p.name, '') AS "book.publisher.name",
+		       COALESCE(p.country, '') AS "book.publisher.country", COALESCE(p.website, '') AS "book.publisher.website"
+		FROM order_items oi
+		JOIN books b ON oi.book_id = b.id
+		LEFT JOIN publishers p ON b.publisher_id = p.id
+		WHERE oi.order_id = $1
+		ORDER BY oi.id`
+
+var ErrOrderNotFound = errors.New("order not found")
+
 type OrderRepository struct {
 	dbTemplate *DBTemplate
 }
 
 func NewOrderRepository(dbTemplate *DBTemplate) *OrderRepository {
 	return &OrderRepository{
-		dbTemplate: dbTemplate,
+		dbTemplate: dbTemplate.Repository("order"),
+	}
+}
+
+func loadOrderItems(template *DBTemplate, orders []Order) error {
+	for i := range orders {
+		items, err := QueryStructs[OrderItem](template, orderItemsQuery, orders[i].ID)
+		if err != nil {
+			return err
+		}
+		orders[i].Items = items
+		orders[i].TaxBreakdown = taxBreakdown(items)
 	}
+	return nil
 }
 
 func (repo *OrderRepository) Create(order Order) (Order, error) {
 	query := `
-		INSERT INTO orders (customer_id, total_price, created_at, status)
-		VALUES ($1, $2, $3, $4) RETURNING id`
-	id, err := ExecuteInsert(repo.dbTemplate, query, order.Customer.ID, order.TotalPrice, order.CreatedAt, order.Status)
+		INSERT INTO orders (customer_id, subtotal, discount_total, tax_total, shipping_total, total_price, created_at, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, order.Customer.ID, order.Subtotal, order.DiscountTotal, order.TaxTotal, order.ShippingTotal, order.TotalPrice, order.CreatedAt, order.Status)
 	if err != nil {
 		return Order{}, err
 	}
@@ -28,31 +61,58 @@ func (repo *OrderRepository) Create(order Order) (Order, error) {
 }
 
 func (repo *OrderRepository) GetById(id int) (Order, error) {
-	query := `
-		SELECT o.id, o.total_price, o.created_at, o.status,
-		       c.id AS "customer.id", c.name AS "customer.name", c.email AS "customer.email"
-		FROM orders o
-		JOIN customers c ON o.customer_id = c.id
+	query := orderSelectQuery + `
 		WHERE o.id = $1`
 	order, err := QueryStruct[Order](repo.dbTemplate, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Order{}, errors.New("order not found")
+			return Order{}, ErrOrderNotFound
 		}
 		return Order{}, err
 	}
-	return *order, nil
+	orders := []Order{*order}
+	if err := loadOrderItems(repo.dbTemplate, orders); err != nil {
+		return Order{}, err
+	}
+	if orders[0].StatusHistory, err = repo.GetStatusHistory(id); err != nil {
+		return Order{}, err
+	}
+	if orders[0].Promotions, err = repo.GetPromotions(id); err != nil {
+		return Order{}, err
+	}
+	return orders[0], nil
 }
 
+// GetCustomerId returns the customer who placed an order.
+func (repo *OrderRepository) GetCustomerId(id int) (int, error) {
+	customerID, err := QueryStruct[int](repo.dbTemplate, `
+		SELECT customer_id FROM orders WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrOrderNotFound
+		}
+		return 0, err
+	}
+	return *customerID, nil
+}
+
+// Update leaves the status untouched, status changes must go through Transition.
 func (repo *OrderRepository) Update(id int, updated Order) (Order, error) {
 	query := `
-		UPDATE orders SET customer_id = $1, total_price = $2, created_at = $3, status = $4
-		WHERE id = $5`
-	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, updated.Customer.ID, updated.TotalPrice, updated.CreatedAt, updated.Status, id)
+		UPDATE orders SET customer_id = $1, subtotal = $2, discount_total = $3, tax_total = $4, shipping_total = $5,
+		                  total_price = $6, created_at = $7
+		WHERE id = $8
+		RETURNING status`
+	status, err := QueryStruct[string](repo.dbTemplate, query, updated.Customer.ID, updated.Subtotal, updated.DiscountTotal, updated.TaxTotal, updated.ShippingTotal,
+		updated.TotalPrice, updated.CreatedAt, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Order{}, ErrOrderNotFound
+		}
 		return Order{}, err
 	}
 	updated.ID = id
+	updated.Status = *status
 	return updated, nil
 }
 
@@ -63,17 +123,13 @@ func (repo *OrderRepository) Delete(id int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("order not found")
+		return ErrOrderNotFound
 	}
 	return nil
 }
 
 func (repo *OrderRepository) GetByCustomerID(customerID int) ([]Order, error) {
-	query := `
-		SELECT o.id, o.total_price, o.created_at, o.status,
-		       c.id AS "customer.id", c.name AS "customer.name", c.email AS "customer.email"
-		FROM orders o
-		JOIN customers c ON o.customer_id = c.id
+	query := orderSelectQuery + `
 		WHERE o.customer_id = $1`
 	orders, err := QueryStructs[Order](repo.dbTemplate, query, customerID)
 	if err != nil {
@@ -83,11 +139,7 @@ func (repo *OrderRepository) GetByCustomerID(customerID int) ([]Order, error) {
 }
 
 func (repo *OrderRepository) GetAll() ([]Order, error) {
-	query := `
-		SELECT o.id, o.total_price, o.created_at, o.status,
-		       c.id AS "customer.id", c.name AS "customer.name", c.email AS "customer.email"
-		FROM orders o
-		JOIN customers c ON o.customer_id = c.id`
+	query := orderSelectQuery
 	orders, err := QueryStructs[Order](repo.dbTemplate, query)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,139 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	OrderPending   = "pending"
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+// orderTransitions lists, for every status, the statuses an order can move to.
+// Cancelled and refunded orders are final.
+var orderTransitions = map[string][]string{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderDelivered, OrderRefunded},
+	OrderDelivered: {OrderRefunded},
+	OrderCancelled: {},
+	OrderRefunded:  {},
+}
+
+// OrderStatuses are all the statuses of an order.
+var OrderStatuses = []string{OrderPending, OrderPaid, OrderShipped, OrderDelivered, OrderCancelled, OrderRefunded}
+
+// RevenueStatuses are the statuses of orders whose money the store keeps.
+var RevenueStatuses = []string{OrderPaid, OrderShipped, OrderDelivered}
+
+var ErrUnknownOrderStatus = errors.New("unknown order status")
+
+type IllegalTransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("order cannot move from %s to %s, allowed: %v", e.From, e.To, e.Allowed)
+}
+
+func IsValidOrderStatus(status string) bool {
+	_, exists := orderTransitions[status]
+	return exists
+}
+
+func CanTransition(from, to string) bool {
+	return slices.Contains(orderTransitions[from], to)
+}
+
+func recordStatusChange(tx *DBTemplate, orderID int, from, to, note string, at time.Time) error {
+	_, err := ExecuteInsert(tx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, note, changed_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`, orderID, from, to, note, at)
+	return err
+}
+
+// refundRemainder records, for every line of an order, a refund of the units and amounts that the
+// refunds of its returns don't cover yet.
+func refundRemainder(tx *DBTemplate, orderID int, at time.Time) error {
+	_, err := ExecuteUpdateOrDelete(tx, `
+		INSERT INTO refunds (order_id, book_id, quantity, amount, tax_amount, created_at)
+		SELECT oi.order_id, oi.book_id, oi.quantity - COALESCE(returned.quantity, 0),
+		       oi.line_total + oi.tax_amount - COALESCE(returned.amount, 0),
+		       oi.tax_amount - COALESCE(returned.tax_amount, 0), $2
+		FROM order_items oi
+		LEFT JOIN (
+			SELECT rr.order_item_id, SUM(r.quantity) AS quantity, SUM(r.amount) AS amount, SUM(r.tax_amount) AS tax_amount
+			FROM refunds r
+			JOIN return_requests rr ON r.return_request_id = rr.id
+			WHERE r.order_id = $1
+			GROUP BY rr.order_item_id
+		) returned ON returned.order_item_id = oi.id
+		WHERE oi.order_id = $1 AND oi.quantity > COALESCE(returned.quantity, 0)`, orderID, at)
+	return err
+}
+
+func (repo *OrderRepository) GetStatusHistory(orderID int) ([]OrderStatusChange, error) {
+	query := `
+		SELECT from_status, to_status, note, changed_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY changed_at, id`
+	return QueryStructs[OrderStatusChange](repo.dbTemplate, query, orderID)
+}
+
+// Transition moves an order to a new status when the lifecycle allows it and records when it happened.
+// Cancelling a pending order, or refunding one that was never shipped, puts its items back in stock.
+// Refunding an order records the refunds of what approved returns haven't already paid back.
+func (repo *OrderRepository) Transition(orderID int, to string, note string) (Order, error) {
+	if !IsValidOrderStatus(to) {
+		return Order{}, ErrUnknownOrderStatus
+	}
+
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		from, err := QueryStruct[string](tx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if !CanTransition(*from, to) {
+			return &IllegalTransitionError{From: *from, To: to, Allowed: orderTransitions[*from]}
+		}
+
+		if _, err := ExecuteUpdateOrDelete(tx, `UPDATE orders SET status = $1 WHERE id = $2`, to, orderID); err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, orderID, *from, to, note, time.Now().UTC()); err != nil {
+			return err
+		}
+
+		if to == OrderRefunded {
+			if err := refundRemainder(tx, orderID, time.Now().UTC()); err != nil {
+				return err
+			}
+		}
+		if to == OrderCancelled || (to == OrderRefunded && *from == OrderPaid) {
+			_, err := ExecuteUpdateOrDelete(tx, `
+				UPDATE books SET stock = books.stock + oi.quantity
+				FROM order_items oi
+				WHERE oi.order_id = $1 AND oi.book_id = books.id`, orderID)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	return repo.GetById(orderID)
+}
@@ -0,0 +1,8 @@
+package data
+
+import "math"
+
+// roundMoney rounds an amount to whole cents, prices are stored as NUMERIC(10, 2).
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPromotionNotFound  = errors.New("promotion not found")
+	ErrPromotionCodeTaken = errors.New("promotion code is already used by another promotion")
+)
+
+const promotionSelectQuery = `
+		SELECT id, name, COALESCE(code, '') AS code, kind, value, scope, scope_value, min_spend,
+		       buy_quantity, get_quantity, usage_limit, per_customer_limit, starts_at, ends_at, active, created_at
+		FROM promotions`
+
+type PromotionRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewPromotionRepository(dbTemplate *DBTemplate) *PromotionRepository {
+	return &PromotionRepository{
+		dbTemplate: dbTemplate.Repository("promotion"),
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == constraint
+}
+
+// normalizePromotionCode makes codes case-insensitive, they are stored upper case.
+func normalizePromotionCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (repo *PromotionRepository) Create(promotion Promotion) (Promotion, error) {
+	promotion.Code = normalizePromotionCode(promotion.Code)
+	promotion.CreatedAt = time.Now().UTC()
+	query := `
+		INSERT INTO promotions (name, code, kind, value, scope, scope_value, min_spend, buy_quantity, get_quantity,
+		                        usage_limit, per_customer_limit, starts_at, ends_at, active, created_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, promotion.Name, promotion.Code, promotion.Kind, promotion.Value, promotion.Scope,
+		promotion.ScopeValue, promotion.MinSpend, promotion.BuyQuantity, promotion.GetQuantity, promotion.UsageLimit,
+		promotion.PerCustomerLimit, promotion.StartsAt, promotion.EndsAt, promotion.Active, promotion.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Promotion{}, ErrPromotionCodeTaken
+		}
+		return Promotion{}, err
+	}
+	promotion.ID = id
+	return promotion, nil
+}
+
+func (repo *PromotionRepository) GetById(id int) (Promotion, error) {
+	promotion, err := QueryStruct[Promotion](repo.dbTemplate, promotionSelectQuery+`
+		WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Promotion{}, ErrPromotionNotFound
+		}
+		return Promotion{}, err
+	}
+	return *promotion, nil
+}
+
+func (repo *PromotionRepository) Update(id int, updated Promotion) (Promotion, error) {
+	updated.Code = normalizePromotionCode(updated.Code)
+	query := `
+		UPDATE promotions SET name = $1, code = NULLIF($2, ''), kind = $3, value = $4, scope = $5, scope_value = $6,
+		                      min_spend = $7, buy_quantity = $8, get_quantity = $9, usage_limit = $10,
+		                      per_customer_limit = $11, starts_at = $12, ends_at = $13, active = $14
+		WHERE id = $15
+		RETURNING created_at`
+	createdAt, err := QueryStruct[time.Time](repo.dbTemplate, query, updated.Name, updated.Code, updated.Kind, updated.Value, updated.Scope,
+		updated.ScopeValue, updated.MinSpend, updated.BuyQuantity, updated.GetQuantity, updated.UsageLimit,
+		updated.PerCustomerLimit, updated.StartsAt, updated.EndsAt, updated.Active, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Promotion{}, ErrPromotionNotFound
+		}
+		if isUniqueViolation(err) {
+			return Promotion{}, ErrPromotionCodeTaken
+		}
+		return Promotion{}, err
+	}
+	updated.ID = id
+	updated.CreatedAt = *createdAt
+	return updated, nil
+}
+
+// Delete deactivates a promotion rather than removing it, its redemptions stay in the customer
+// usage counts and the sales reports.
+func (repo *PromotionRepository) Delete(id int) error {
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, `UPDATE promotions SET active = FALSE WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
+
+func (repo *PromotionRepository) GetAll() ([]Promotion, error) {
+	return QueryStructs[Promotion](repo.dbTemplate, promotionSelectQuery+`
+		ORDER BY id`)
+}
+
+// GetPromotionSalesInTimeRange sums the redemptions of each promotion over the revenue-bearing orders of a period.
+func GetPromotionSalesInTimeRange(start, end time.Time, template *DBTemplate) ([]PromotionSales, error) {
+	query := `
+		SELECT pr.promotion_id, p.name, COALESCE(p.code, '') AS code,
+		       COUNT(*) AS redemptions, SUM(pr.amount) AS discount_total
+		FROM promotion_redemptions pr
+		JOIN promotions p ON pr.promotion_id = p.id
+		JOIN orders o ON pr.order_id = o.id
+		WHERE o.created_at BETWEEN $1 AND $2 AND o.status = ANY($3)
+		GROUP BY pr.promotion_id, p.name, p.code
+		ORDER BY discount_total DESC`
+	return QueryStructs[PromotionSales](template, query, start, end, pq.Array(RevenueStatuses))
+}
@@ -0,0 +1,310 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	PromotionPercent     = "percent"
+	PromotionFixed       = "fixed"
+	PromotionBuyXGetY    = "buy_x_get_y"
+	PromotionScopeOrder  = "order"
+	PromotionScopeGenre  = "genre"
+	PromotionScopeAuthor = "author"
+	PromotionScopeBook   = "book"
+)
+
+var (
+	PromotionKinds  = []string{PromotionPercent, PromotionFixed, PromotionBuyXGetY}
+	PromotionScopes = []string{PromotionScopeOrder, PromotionScopeGenre, PromotionScopeAuthor, PromotionScopeBook}
+)
+
+// UnknownPromotionCodeError is returned when a checkout names a code no promotion uses.
+type UnknownPromotionCodeError struct {
+	Code string
+}
+
+func (e *UnknownPromotionCodeError) Error() string {
+	return fmt.Sprintf("unknown promotion code %s", e.Code)
+}
+
+type promotionUsage struct {
+	PromotionID int `db:"promotion_id"`
+	Total       int `db:"total"`
+	ByCustomer  int `db:"by_customer"`
+}
+
+// loadPromotions returns the automatic promotions plus the ones matching the given codes, with their usage.
+// Redemptions of cancelled orders do not count towards the usage limits.
+// Only the promotions with a usage limit that apply to the items are locked, until the end of the
+// transaction, so those limits hold under concurrent checkouts without serializing every checkout.
+func loadPromotions(tx *DBTemplate, codes []string, customerID int, items []OrderItem, now time.Time) ([]Promotion, map[int]promotionUsage, error) {
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if code = normalizePromotionCode(code); code != "" && !slices.Contains(normalized, code) {
+			normalized = append(normalized, code)
+		}
+	}
+
+	promotions, err := QueryStructs[Promotion](tx, promotionSelectQuery+`
+		WHERE code IS NULL OR code = ANY($1)
+		ORDER BY code NULLS FIRST, id`, pq.Array(normalized))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, code := range normalized {
+		if !slices.ContainsFunc(promotions, func(p Promotion) bool { return p.Code == code }) {
+			return nil, nil, &UnknownPromotionCodeError{Code: code}
+		}
+	}
+
+	var limited []int
+	for _, promotion := range promotions {
+		if needsLock(promotion, items, now) {
+			limited = append(limited, promotion.ID)
+		}
+	}
+	if len(limited) > 0 {
+		// The locked rows are read again, they may have changed before the lock was taken.
+		locked, err := QueryStructs[Promotion](tx, promotionSelectQuery+`
+			WHERE id = ANY($1)
+			ORDER BY id
+			FOR UPDATE`, pq.Array(limited))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, promotion := range locked {
+			i := slices.IndexFunc(promotions, func(p Promotion) bool { return p.ID == promotion.ID })
+			promotions[i] = promotion
+		}
+	}
+
+	ids := make([]int, len(promotions))
+	for i, promotion := range promotions {
+		ids[i] = promotion.ID
+	}
+	usages, err := QueryStructs[promotionUsage](tx, `
+		SELECT pr.promotion_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE pr.customer_id = $2) AS by_customer
+		FROM promotion_redemptions pr
+		JOIN orders o ON pr.order_id = o.id
+		WHERE pr.promotion_id = ANY($1) AND o.status <> $3
+		GROUP BY pr.promotion_id`, pq.Array(ids), customerID, OrderCancelled)
+	if err != nil {
+		return nil, nil, err
+	}
+	usage := make(map[int]promotionUsage, len(usages))
+	for _, u := range usages {
+		usage[u.PromotionID] = u
+	}
+	return promotions, usage, nil
+}
+
+// needsLock tells whether a promotion has a usage limit and could apply to the items, only those
+// promotions have usage that concurrent checkouts must not both count.
+func needsLock(promotion Promotion, items []OrderItem, now time.Time) bool {
+	if promotion.UsageLimit == 0 && promotion.PerCustomerLimit == 0 {
+		return false
+	}
+	if !promotion.Active || promotion.StartsAt != nil && now.Before(*promotion.StartsAt) || promotion.EndsAt != nil && !now.Before(*promotion.EndsAt) {
+		return false
+	}
+	return slices.ContainsFunc(items, func(item OrderItem) bool { return promotionMatches(promotion, item.Book) })
+}
+
+// ineligibility returns why a promotion cannot apply to an order, or an empty string when it can.
+func ineligibility(promotion Promotion, usage promotionUsage, subtotal float64, now time.Time) string {
+	switch {
+	case !promotion.Active:
+		return "promotion is not active"
+	case promotion.StartsAt != nil && now.Before(*promotion.StartsAt):
+		return "promotion has not started yet"
+	case promotion.EndsAt != nil && !now.Before(*promotion.EndsAt):
+		return "promotion has expired"
+	case promotion.UsageLimit > 0 && usage.Total >= promotion.UsageLimit:
+		return "promotion usage limit reached"
+	case promotion.PerCustomerLimit > 0 && usage.ByCustomer >= promotion.PerCustomerLimit:
+		return "promotion already used the maximum number of times by this customer"
+	case subtotal < promotion.MinSpend:
+		return fmt.Sprintf("minimum spend of %.2f not reached", promotion.MinSpend)
+	}
+	return ""
+}
+
+func promotionMatches(promotion Promotion, book Book) bool {
+	switch promotion.Scope {
+	case PromotionScopeGenre:
+		return slices.ContainsFunc(book.Genres, func(genre string) bool {
+			return strings.EqualFold(strings.TrimSpace(genre), promotion.ScopeValue)
+		})
+	case PromotionScopeAuthor:
+		return strconv.Itoa(book.Author.ID) == promotion.ScopeValue
+	case PromotionScopeBook:
+		return strconv.Itoa(book.ID) == promotion.ScopeValue
+	}
+	return true
+}
+
+// DescribePromotion explains a promotion rule in plain words, the explanation is kept with each redemption.
+func DescribePromotion(promotion Promotion) string {
+	var target string
+	switch promotion.Scope {
+	case PromotionScopeGenre:
+		target = fmt.Sprintf("books in genre %q", promotion.ScopeValue)
+	case PromotionScopeAuthor:
+		target = "books by author #" + promotion.ScopeValue
+	case PromotionScopeBook:
+		target = "book #" + promotion.ScopeValue
+	default:
+		target = "the order"
+	}
+
+	var description string
+	switch promotion.Kind {
+	case PromotionPercent:
+		description = fmt.Sprintf("%s%% off %s", strconv.FormatFloat(promotion.Value, 'f', -1, 64), target)
+	case PromotionFixed:
+		description = fmt.Sprintf("%.2f off %s", promotion.Value, target)
+	case PromotionBuyXGetY:
+		description = fmt.Sprintf("buy %d get %d free on %s", promotion.BuyQuantity, promotion.GetQuantity, target)
+	}
+	if promotion.MinSpend > 0 {
+		description += fmt.Sprintf(" with a minimum spend of %.2f", promotion.MinSpend)
+	}
+	return description
+}
+
+// promotionDiscounts computes the discount a promotion gives on each order item, indexed like the items.
+// Discounts are taken from what is left of each line after the promotions applied before.
+func promotionDiscounts(promotion Promotion, items []OrderItem) []float64 {
+	discounts := make([]float64, len(items))
+	remaining := make([]float64, len(items))
+	var matched []int
+	for i, item := range items {
+		remaining[i] = item.UnitPrice*float64(item.Quantity) - item.DiscountAmount
+		if promotionMatches(promotion, item.Book) && remaining[i] > 0 {
+			matched = append(matched, i)
+		}
+	}
+
+	switch promotion.Kind {
+	case PromotionPercent:
+		for _, i := range matched {
+			discounts[i] = roundMoney(remaining[i] * promotion.Value / 100)
+		}
+
+	case PromotionFixed:
+		base := 0.0
+		for _, i := range matched {
+			base += remaining[i]
+		}
+		amount := min(promotion.Value, base)
+		allocated := 0.0
+		for n, i := range matched {
+			if n == len(matched)-1 {
+				discounts[i] = roundMoney(amount - allocated)
+				break
+			}
+			discounts[i] = roundMoney(amount * remaining[i] / base)
+			allocated += discounts[i]
+		}
+
+	case PromotionBuyXGetY:
+		// Every group of buy+get units, most expensive first, gets its cheapest units for free.
+		type unit struct {
+			item  int
+			price float64
+		}
+		var units []unit
+		for _, i := range matched {
+			for q := 0; q < items[i].Quantity; q++ {
+				units = append(units, unit{item: i, price: remaining[i] / float64(items[i].Quantity)})
+			}
+		}
+		sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+
+		group := promotion.BuyQuantity + promotion.GetQuantity
+		if group > 0 {
+			for n := range units {
+				if n%group >= promotion.BuyQuantity && n-n%group+group <= len(units) {
+					discounts[units[n].item] += units[n].price
+				}
+			}
+			for i := range discounts {
+				discounts[i] = roundMoney(discounts[i])
+			}
+		}
+	}
+	return discounts
+}
+
+// applyPromotions runs the promotions, automatic ones first, and adds their discounts to the order items.
+// Every promotion that gave a discount is returned, as well as the reason why each requested code did not.
+func applyPromotions(order *Order, promotions []Promotion, usage map[int]promotionUsage, now time.Time) ([]AppliedPromotion, []SkippedPromotion) {
+	subtotal := 0.0
+	for _, item := range order.Items {
+		subtotal += item.UnitPrice * float64(item.Quantity)
+	}
+
+	applied := []AppliedPromotion{}
+	var skipped []SkippedPromotion
+	for _, promotion := range promotions {
+		if reason := ineligibility(promotion, usage[promotion.ID], subtotal, now); reason != "" {
+			if promotion.Code != "" {
+				skipped = append(skipped, SkippedPromotion{Code: promotion.Code, Reason: reason})
+			}
+			continue
+		}
+
+		discounts := promotionDiscounts(promotion, order.Items)
+		amount := 0.0
+		for i, discount := range discounts {
+			order.Items[i].DiscountAmount = roundMoney(order.Items[i].DiscountAmount + discount)
+			amount += discount
+		}
+		if amount <= 0 {
+			if promotion.Code != "" {
+				skipped = append(skipped, SkippedPromotion{Code: promotion.Code, Reason: "no item of the order qualifies"})
+			}
+			continue
+		}
+
+		applied = append(applied, AppliedPromotion{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			Code:        promotion.Code,
+			Amount:      roundMoney(amount),
+			Explanation: DescribePromotion(promotion),
+		})
+	}
+	return applied, skipped
+}
+
+func recordRedemptions(tx *DBTemplate, order Order) error {
+	for _, promotion := range order.Promotions {
+		_, err := ExecuteInsert(tx, `
+			INSERT INTO promotion_redemptions (promotion_id, customer_id, order_id, amount, explanation, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+			promotion.PromotionID, order.Customer.ID, order.ID, promotion.Amount, promotion.Explanation, order.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *OrderRepository) GetPromotions(orderID int) ([]AppliedPromotion, error) {
+	query := `
+		SELECT pr.promotion_id, p.name, COALESCE(p.code, '') AS code, pr.amount, pr.explanation
+		FROM promotion_redemptions pr
+		JOIN promotions p ON pr.promotion_id = p.id
+		WHERE pr.order_id = $1
+		ORDER BY pr.id`
+	return QueryStructs[AppliedPromotion](repo.dbTemplate, query, orderID)
+}
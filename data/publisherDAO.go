@@ -0,0 +1,93 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrPublisherNotFound  = errors.New("publisher not found")
+	ErrPublisherNameTaken = errors.New("publisher name is already taken")
+)
+
+type PublisherRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewPublisherRepository(dbTemplate *DBTemplate) *PublisherRepository {
+	return &PublisherRepository{
+		dbTemplate: dbTemplate.Repository("publisher"),
+	}
+}
+
+func (repo *PublisherRepository) Create(publisher Publisher) (Publisher, error) {
+	query := `
+		INSERT INTO publishers (name, country, website)
+		VALUES ($1, $2, $3) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, publisher.Name, publisher.Country, publisher.Website)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Publisher{}, ErrPublisherNameTaken
+		}
+		return Publisher{}, err
+	}
+	publisher.ID = id
+	return publisher, nil
+}
+
+func (repo *PublisherRepository) GetById(id int) (Publisher, error) {
+	query := `
+		SELECT id, name, country, website
+		FROM publishers
+		WHERE id = $1`
+	publisher, err := QueryStruct[Publisher](repo.dbTemplate, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Publisher{}, ErrPublisherNotFound
+		}
+		return Publisher{}, err
+	}
+	return *publisher, nil
+}
+
+func (repo *PublisherRepository) Update(id int, updated Publisher) (Publisher, error) {
+	query := `
+		UPDATE publishers SET name = $1, country = $2, website = $3
+		WHERE id = $4`
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, updated.Name, updated.Country, updated.Website, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Publisher{}, ErrPublisherNameTaken
+		}
+		return Publisher{}, err
+	}
+	if rowsAffected == 0 {
+		return Publisher{}, ErrPublisherNotFound
+	}
+	updated.ID = id
+	return updated, nil
+}
+
+func (repo *PublisherRepository) Delete(id int) error {
+	query := `DELETE FROM publishers WHERE id = $1`
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, id)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrPublisherNotFound
+	}
+	return nil
+}
+
+func (repo *PublisherRepository) GetAll() ([]Publisher, error) {
+	query := `
+		SELECT id, name, country, website
+		FROM publishers
+		ORDER BY name`
+	publishers, err := QueryStructs[Publisher](repo.dbTemplate, query)
+	if err != nil {
+		return nil, err
+	}
+	return publishers, nil
+}
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"finalproject/ratelimit"
+	"log"
+	"time"
+)
+
+// PostgresRateLimitStore keeps the rate limit buckets in Postgres, so every instance shares them.
+type PostgresRateLimitStore struct {
+	dbTemplate *DBTemplate
+}
+
+func NewPostgresRateLimitStore(dbTemplate *DBTemplate) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{
+		dbTemplate: dbTemplate.Repository("rate_limit"),
+	}
+}
+
+type bucketState struct {
+	Tokens  float64 `db:"tokens"`
+	Allowed bool    `db:"allowed"`
+}
+
+// Take refills and takes from the bucket in a single statement, so concurrent requests of several
+// instances can't take the same token. The SET expressions all see the bucket as it was before the update.
+func (store *PostgresRateLimitStore) Take(key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	refilled := `LEAST($2::float8, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM ($4::timestamp - b.updated_at)))::float8 * $3::float8)`
+	query := `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, $4::timestamp)
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = ` + refilled + ` - CASE WHEN ` + refilled + ` >= 1 THEN 1 ELSE 0 END,
+			allowed = ` + refilled + ` >= 1,
+			updated_at = GREATEST(b.updated_at, $4::timestamp)
+		RETURNING b.tokens, b.allowed`
+	state, err := QueryStruct[bucketState](store.dbTemplate, query, key, float64(limit.Requests), limit.Rate(), now)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return ratelimit.NewResult(state.Allowed, state.Tokens, limit), nil
+}
+
+// DeleteIdle removes the buckets unused since before a given time.
+func (store *PostgresRateLimitStore) DeleteIdle(before time.Time) (int, error) {
+	return ExecuteUpdateOrDelete(store.dbTemplate, `DELETE FROM rate_limit_buckets WHERE updated_at <= $1`, before)
+}
+
+// StartRateLimitJanitor periodically removes the buckets unused for a day, they have long refilled.
+func StartRateLimitJanitor(store *PostgresRateLimitStore) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := store.DeleteIdle(time.Now().UTC().Add(-24 * time.Hour)); err != nil {
+			log.Println("Error removing idle rate limit buckets:", err)
+		}
+	}
+}
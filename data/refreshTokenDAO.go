@@ -0,0 +1,152 @@
+package data
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+	// ErrRefreshTokenReused is returned when an already used token comes back, the token was
+	// probably stolen and its whole family is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token was already used, the session is revoked")
+)
+
+type RefreshTokenRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewRefreshTokenRepository(dbTemplate *DBTemplate) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		dbTemplate: dbTemplate.Repository("refresh_token"),
+	}
+}
+
+func (repo *RefreshTokenRepository) insert(template *DBTemplate, familyID string, userID int, scope string, expiresAt time.Time) (RefreshToken, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return RefreshToken{}, "", err
+	}
+	token := hex.EncodeToString(secret)
+	now := time.Now().UTC()
+	refreshToken := RefreshToken{
+		FamilyID:  familyID,
+		UserID:    userID,
+		Scope:     scope,
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
+	}
+	_, err := ExecuteUpdateOrDelete(template, `
+		INSERT INTO refresh_tokens (token_hash, family_id, user_id, scope, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`, hashToken(token), refreshToken.FamilyID, refreshToken.UserID,
+		refreshToken.Scope, refreshToken.IssuedAt, refreshToken.ExpiresAt)
+	if err != nil {
+		return RefreshToken{}, "", err
+	}
+	return refreshToken, token, nil
+}
+
+// Issue starts the token family of a login session, its tokens expire with the session.
+func (repo *RefreshTokenRepository) Issue(session Session, scope string) (RefreshToken, string, error) {
+	return repo.insert(repo.dbTemplate, session.ID, session.UserID, scope, session.ExpiresAt)
+}
+
+// Rotate uses up a refresh token and returns its replacement in the same family.
+func (repo *RefreshTokenRepository) Rotate(token string) (RefreshToken, string, error) {
+	var rotated RefreshToken
+	var newToken string
+	reused := false
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		current, err := QueryStruct[RefreshToken](tx, `
+			SELECT family_id, user_id, scope, issued_at, expires_at, used_at, revoked_at
+			FROM refresh_tokens
+			WHERE token_hash = $1
+			FOR UPDATE`, hashToken(token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		now := time.Now().UTC()
+		if current.RevokedAt != nil || !current.ExpiresAt.After(now) {
+			return ErrInvalidRefreshToken
+		}
+		if current.UsedAt != nil {
+			reused = true
+			return nil
+		}
+
+		if _, err := ExecuteUpdateOrDelete(tx, `
+			UPDATE refresh_tokens SET used_at = $1
+			WHERE token_hash = $2`, now, hashToken(token)); err != nil {
+			return err
+		}
+		rotated, newToken, err = repo.insert(tx, current.FamilyID, current.UserID, current.Scope, current.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		return RefreshToken{}, "", err
+	}
+	if reused {
+		// Revoked outside of the lookup transaction, so the revocation is kept.
+		if _, err := ExecuteUpdateOrDelete(repo.dbTemplate, `
+			UPDATE refresh_tokens SET revoked_at = $1
+			WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $2) AND revoked_at IS NULL`,
+			time.Now().UTC(), hashToken(token)); err != nil {
+			return RefreshToken{}, "", err
+		}
+		return RefreshToken{}, "", ErrRefreshTokenReused
+	}
+	return rotated, newToken, nil
+}
+
+// RevokeFamily revokes the tokens of a session.
+func (repo *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, `
+		UPDATE refresh_tokens SET revoked_at = $1
+		WHERE family_id::text = $2 AND revoked_at IS NULL`, time.Now().UTC(), familyID)
+	return err
+}
+
+// RevokeUser revokes every token family of a user except keepFamilyID, which may be empty.
+func (repo *RefreshTokenRepository) RevokeUser(userID int, keepFamilyID string) error {
+	_, err := ExecuteUpdateOrDelete(repo.dbTemplate, `
+		UPDATE refresh_tokens SET revoked_at = $1
+		WHERE user_id = $2 AND family_id::text <> $3 AND revoked_at IS NULL`, time.Now().UTC(), userID, keepFamilyID)
+	return err
+}
+
+// DeleteExpired removes the tokens that expired, they can no longer be used nor reused.
+// CountActive counts the refresh tokens that can still be exchanged.
+func (repo *RefreshTokenRepository) CountActive() (int, error) {
+	count, err := QueryStruct[int](repo.dbTemplate, `
+		SELECT COUNT(*) FROM refresh_tokens
+		WHERE used_at IS NULL AND revoked_at IS NULL AND expires_at > $1`, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return *count, nil
+}
+
+func (repo *RefreshTokenRepository) DeleteExpired() (int, error) {
+	return ExecuteUpdateOrDelete(repo.dbTemplate, `DELETE FROM refresh_tokens WHERE expires_at <= $1`, time.Now().UTC())
+}
+
+// StartRefreshTokenJanitor periodically removes the expired refresh tokens.
+func StartRefreshTokenJanitor(store *DBTemplate) {
+	repo := NewRefreshTokenRepository(store)
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := repo.DeleteExpired(); err != nil {
+			log.Println("Error removing expired refresh tokens:", err)
+		}
+	}
+}
@@ -1,37 +1,32 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"finalproject/metrics"
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"sort"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 func GetOrdersInTimeRange(start, end time.Time, repo *OrderRepository) ([]Order, error) {
-	query := `
-		SELECT o.id, o.total_price, o.created_at, o.status,
-		       c.id AS "customer.id", c.name AS "customer.name", c.email AS "customer.email"
-		FROM orders o
-		JOIN customers c ON o.customer_id = c.id
+	query := orderSelectQuery + `
 		WHERE o.created_at BETWEEN $1 AND $2`
 	orders, err := QueryStructs[Order](repo.dbTemplate, query, start, end)
 	if err != nil {
 		return nil, err
 	}
 
-	// Fetch order items for each order
-	for i := range orders {
-		orderItemsQuery := `
-			SELECT oi.book_id, b.title, oi.quantity
-			FROM order_items oi
-			JOIN books b ON oi.book_id = b.id
-			WHERE oi.order_id = $1`
-		items, err := QueryStructs[OrderItem](repo.dbTemplate, orderItemsQuery, orders[i].ID)
-		if err != nil {
-			return nil, err
-		}
-		orders[i].Items = items
+	if err := loadOrderItems(repo.dbTemplate, orders); err != nil {
+		return nil, err
 	}
 
 	return orders, nil
@@ -41,10 +36,35 @@ func generateSalesReport(repo *OrderRepository) (SalesReport, error) {
 	now := time.Now()
 	start := now.Add(-24 * time.Hour)
 
-	orders, err := GetOrdersInTimeRange(start, now, repo)
+	allOrders, err := GetOrdersInTimeRange(start, now, repo)
 	if err != nil {
 		return SalesReport{}, err
 	}
+	refunds, err := GetRefundsInTimeRange(start, now, repo.dbTemplate)
+	if err != nil {
+		return SalesReport{}, err
+	}
+
+	report := summarizeSales(now, allOrders, refunds)
+	if report.Promotions, err = GetPromotionSalesInTimeRange(start, now, repo.dbTemplate); err != nil {
+		return SalesReport{}, err
+	}
+
+	return report, nil
+}
+
+// summarizeSales builds the sales report of the orders placed and the refunds issued during a period.
+func summarizeSales(now time.Time, allOrders []Order, refunds []Refund) SalesReport {
+	// Pending, cancelled and refunded orders did not bring in any money.
+	var orders []Order
+	leftOut := make(map[int]bool)
+	for _, order := range allOrders {
+		if slices.Contains(RevenueStatuses, order.Status) {
+			orders = append(orders, order)
+		} else {
+			leftOut[order.ID] = true
+		}
+	}
 
 	report := SalesReport{
 		Timestamp:    now,
@@ -53,28 +73,77 @@ func generateSalesReport(repo *OrderRepository) (SalesReport, error) {
 	}
 
 	bookSalesMap := make(map[int]*BookSales)
+	publisherSalesMap := make(map[int]*PublisherSales)
+	bookSales := func(book Book) *BookSales {
+		if _, exists := bookSalesMap[book.ID]; !exists {
+			bookSalesMap[book.ID] = &BookSales{
+				Book:     book,
+				Quantity: 0,
+			}
+		}
+		return bookSalesMap[book.ID]
+	}
+	publisherSales := func(publisher Publisher) *PublisherSales {
+		if _, exists := publisherSalesMap[publisher.ID]; !exists {
+			publisherSalesMap[publisher.ID] = &PublisherSales{
+				Publisher: publisher,
+			}
+		}
+		return publisherSalesMap[publisher.ID]
+	}
+
 	for _, order := range orders {
 		report.TotalRevenue += order.TotalPrice
+		report.TotalTax += order.TaxTotal
+		report.TotalDiscounts += order.DiscountTotal
 		for _, item := range order.Items {
-			if _, exists := bookSalesMap[item.Book.ID]; !exists {
-				bookSalesMap[item.Book.ID] = &BookSales{
-					Book:     item.Book,
-					Quantity: 0,
-				}
+			bookSales(item.Book).Quantity += item.Quantity
+
+			lineTotal := item.LineTotal
+			if item.UnitPrice == 0 {
+				// Orders placed before line prices were recorded fall back to the current book price.
+				lineTotal = float64(item.Quantity) * item.Book.Price
 			}
-			bookSalesMap[item.Book.ID].Quantity += item.Quantity
+			publisherSales(item.Book.Publisher).Quantity += item.Quantity
+			publisherSales(item.Book.Publisher).Revenue += lineTotal
 		}
 	}
 
+	// Refunds issued during the period are taken off, even when the order itself is older. Orders
+	// of the period that were left out of the revenue have nothing to take their refunds off.
+	for _, refund := range refunds {
+		if leftOut[refund.OrderID] {
+			continue
+		}
+		report.TotalRefunds += refund.Amount
+		report.TotalTax -= refund.TaxAmount
+		bookSales(refund.Book).Quantity -= refund.Quantity
+		publisherSales(refund.Book.Publisher).Quantity -= refund.Quantity
+		publisherSales(refund.Book.Publisher).Revenue -= refund.Amount - refund.TaxAmount
+	}
+	report.TotalRefunds = roundMoney(report.TotalRefunds)
+	report.TotalTax = roundMoney(report.TotalTax)
+	report.TotalDiscounts = roundMoney(report.TotalDiscounts)
+	report.TotalRevenue = roundMoney(report.TotalRevenue - report.TotalRefunds)
+	report.NetRevenue = roundMoney(report.TotalRevenue - report.TotalTax)
+
 	for _, sales := range bookSalesMap {
 		report.TopSellingBooks = append(report.TopSellingBooks, *sales)
 	}
 
-	return report, nil
+	// Books without a publisher are grouped under the zero publisher (id 0).
+	for _, sales := range publisherSalesMap {
+		report.SalesByPublisher = append(report.SalesByPublisher, *sales)
+	}
+	sort.Slice(report.SalesByPublisher, func(i, j int) bool {
+		return report.SalesByPublisher[i].Revenue > report.SalesByPublisher[j].Revenue
+	})
+
+	return report
 }
 
 func saveReport(report SalesReport) error {
-	filename := fmt.Sprintf("output-reports/report_%s.json", report.Timestamp.Format("20060102150405"))
+	filename := fmt.Sprintf("%s/report_%s.json", reportsDir, report.Timestamp.Format("20060102150405"))
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -84,27 +153,116 @@ func saveReport(report SalesReport) error {
 	return json.NewEncoder(file).Encode(report)
 }
 
+var (
+	// reportsDir is where the sales reports are saved.
+	reportsDir = "output-reports"
+	// reportInterval is the time between two sales reports.
+	reportInterval = 24 * time.Second
+
+	// reportHeartbeat is the last time, in Unix nanoseconds, the report generator started or finished a run.
+	reportHeartbeat atomic.Int64
+	// reportsWriteCheck is the outcome of the write check of the reports directory made on the last heartbeat.
+	reportsWriteCheck atomic.Pointer[writeCheck]
+
+	reportDuration = metrics.NewHistogramVec("report_generation_duration_seconds", "Duration of the sales report generations by outcome.", metrics.DefaultBuckets, "outcome")
+	reportRuns     = metrics.NewCounterVec("report_generations_total", "Sales report generations by outcome: success, generation_failed or save_failed.", "outcome")
+)
+
 func StartReportGenerator(store *DBTemplate) {
-	dao, err := GetDAO[Order]("order", store)
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	reportGeneratorBeat()
+	for range ticker.C {
+		generateAndSaveReport(store)
+		reportGeneratorBeat()
+	}
+}
+
+type writeCheck struct {
+	err error
+}
+
+// reportGeneratorBeat records that the report generator is alive, and checks the reports directory
+// is still writable for the next run.
+func reportGeneratorBeat() {
+	reportsWriteCheck.Store(&writeCheck{err: checkReportsWritable()})
+	reportHeartbeat.Store(time.Now().UnixNano())
+}
+
+// ReportGeneratorHeartbeat returns when the report generator last started or finished a run,
+// the zero time if it never started.
+func ReportGeneratorHeartbeat() time.Time {
+	nanos := reportHeartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// ConfigureReports sets where the sales reports are saved and how often. Call it before StartReportGenerator.
+func ConfigureReports(dir string, interval time.Duration) {
+	reportsDir = dir
+	reportInterval = interval
+}
+
+// ReportsDir returns the directory of the sales reports.
+func ReportsDir() string {
+	return reportsDir
+}
+
+// ReportInterval returns the time between two sales reports.
+func ReportInterval() time.Duration {
+	return reportInterval
+}
+
+// ReportsWritable returns the outcome of the last check that a report can be written to the reports
+// directory. The report generator checks it on each heartbeat, so probes don't write files.
+func ReportsWritable() error {
+	check := reportsWriteCheck.Load()
+	if check == nil {
+		return errors.New("reports directory not checked yet")
+	}
+	return check.err
+}
+
+// checkReportsWritable checks that a report can be written to the reports directory.
+func checkReportsWritable() error {
+	file, err := os.CreateTemp(reportsDir, ".write-check-*")
 	if err != nil {
-		log.Fatalf("Failed to get DAO for order: %v", err)
+		return err
 	}
-	repo, ok := dao.(*OrderRepository)
-	if !ok {
-		log.Fatalf("Failed to cast DAO to *OrderRepository")
+	name := file.Name()
+	_, err = file.WriteString("ok")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
 	}
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
 
-	ticker := time.NewTicker(24 * time.Second)
-	defer ticker.Stop()
+// generateAndSaveReport runs one report generation in its own trace, the queries of the run are its children.
+func generateAndSaveReport(store *DBTemplate) {
+	ctx, span := tracer.Start(context.Background(), "report generation")
+	defer span.End()
 
-	for range ticker.C {
-		report, err := generateSalesReport(repo)
-		if err != nil {
-			log.Println("Error generating sales report:", err)
-			continue
-		}
-		if err := saveReport(report); err != nil {
-			log.Println("Error saving sales report:", err)
-		}
+	start := time.Now()
+	outcome := "success"
+	report, err := generateSalesReport(NewOrderRepository(store.WithContext(ctx)))
+	if err != nil {
+		log.Println("Error generating sales report:", err)
+		outcome = "generation_failed"
+	} else if err = saveReport(report); err != nil {
+		log.Println("Error saving sales report:", err)
+		outcome = "save_failed"
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
+	span.SetAttributes(attribute.String("report.outcome", outcome))
+	reportDuration.With(outcome).Observe(time.Since(start).Seconds())
+	reportRuns.With(outcome).Inc()
 }
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeSalesTakesOffRefundsOfOlderOrders(t *testing.T) {
+	now := time.Now()
+	publisher := Publisher{ID: 1, Name: "Penguin"}
+	book := Book{ID: 7, Title: "Dune", Price: 20, Publisher: publisher}
+
+	// The order was placed before the period and refunded during it, so only the refund shows up.
+	refunds := []Refund{{ID: 1, OrderID: 42, Book: book, Quantity: 1, Amount: 22, TaxAmount: 2, CreatedAt: now.Add(-time.Hour)}}
+	report := summarizeSales(now, nil, refunds)
+
+	if report.TotalRefunds != 22 {
+		t.Errorf("TotalRefunds = %v, want 22", report.TotalRefunds)
+	}
+	if report.TotalRevenue != -22 {
+		t.Errorf("TotalRevenue = %v, want -22", report.TotalRevenue)
+	}
+	if report.TotalTax != -2 {
+		t.Errorf("TotalTax = %v, want -2", report.TotalTax)
+	}
+	if len(report.SalesByPublisher) != 1 || report.SalesByPublisher[0].Revenue != -20 || report.SalesByPublisher[0].Quantity != -1 {
+		t.Errorf("SalesByPublisher = %+v, want revenue -20 and quantity -1 for %s", report.SalesByPublisher, publisher.Name)
+	}
+}
+
+func TestSummarizeSalesSkipsRefundsOfOrdersLeftOut(t *testing.T) {
+	now := time.Now()
+	book := Book{ID: 7, Title: "Dune", Price: 20}
+
+	// Placed and refunded during the period: neither the order nor its refund counts.
+	orders := []Order{{
+		ID:         42,
+		Status:     OrderRefunded,
+		TotalPrice: 22,
+		TaxTotal:   2,
+		CreatedAt:  now.Add(-2 * time.Hour),
+		Items:      []OrderItem{{Book: book, Quantity: 1, UnitPrice: 20, LineTotal: 20}},
+	}}
+	refunds := []Refund{{ID: 1, OrderID: 42, Book: book, Quantity: 1, Amount: 22, TaxAmount: 2, CreatedAt: now.Add(-time.Hour)}}
+	report := summarizeSales(now, orders, refunds)
+
+	if report.TotalOrders != 0 || report.TotalRevenue != 0 || report.TotalRefunds != 0 || report.TotalTax != 0 {
+		t.Errorf("report = %+v, want no orders, revenue, refunds or tax", report)
+	}
+}
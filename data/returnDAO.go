@@ -0,0 +1,265 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+)
+
+// Only goods that left the warehouse can be returned.
+var returnableOrderStatuses = []string{OrderShipped, OrderDelivered}
+
+var (
+	ErrReturnNotFound       = errors.New("return request not found")
+	ErrReturnAlreadyDecided = errors.New("return request has already been decided")
+	ErrOrderItemNotFound    = errors.New("order item not found")
+)
+
+type ReturnNotAllowedError struct {
+	Status string
+}
+
+func (e *ReturnNotAllowedError) Error() string {
+	return fmt.Sprintf("items of a %s order cannot be returned", e.Status)
+}
+
+type ReturnQuantityError struct {
+	Requested int
+	Remaining int
+}
+
+func (e *ReturnQuantityError) Error() string {
+	return fmt.Sprintf("cannot return %d items, only %d left to return", e.Requested, e.Remaining)
+}
+
+type returnableItem struct {
+	OrderID     int     `db:"order_id"`
+	OrderStatus string  `db:"status"`
+	BookID      int     `db:"book_id"`
+	Quantity    int     `db:"quantity"`
+	LineTotal   float64 `db:"line_total"`
+	TaxAmount   float64 `db:"tax_amount"`
+	Returned    int     `db:"-"`
+}
+
+const returnSelectQuery = `
+		SELECT id, order_id, order_item_id, quantity, reason, status, decision_note, created_at, decided_at
+		FROM return_requests`
+
+type ReturnRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewReturnRepository(dbTemplate *DBTemplate) *ReturnRepository {
+	return &ReturnRepository{
+		dbTemplate: dbTemplate.Repository("return"),
+	}
+}
+
+// lockReturnableItem locks an order item and reports how many of its units are already
+// being returned, rejected requests excluded. The sum is read after the lock is taken so
+// concurrent requests for the same item see each other.
+func lockReturnableItem(tx *DBTemplate, orderID int, orderItemID int) (returnableItem, error) {
+	item, err := QueryStruct[returnableItem](tx, `
+		SELECT oi.order_id, o.status, oi.book_id, oi.quantity, oi.line_total, oi.tax_amount
+		FROM order_items oi
+		JOIN orders o ON oi.order_id = o.id
+		WHERE oi.id = $1 AND oi.order_id = $2
+		FOR UPDATE OF oi`, orderItemID, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return returnableItem{}, ErrOrderItemNotFound
+		}
+		return returnableItem{}, err
+	}
+
+	returned, err := QueryStruct[int](tx, `
+		SELECT COALESCE(SUM(quantity), 0) FROM return_requests
+		WHERE order_item_id = $1 AND status <> $2`, orderItemID, ReturnRejected)
+	if err != nil {
+		return returnableItem{}, err
+	}
+	item.Returned = *returned
+	return *item, nil
+}
+
+func (repo *ReturnRepository) Create(request ReturnRequest) (ReturnRequest, error) {
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		item, err := lockReturnableItem(tx, request.OrderID, request.OrderItemID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(returnableOrderStatuses, item.OrderStatus) {
+			return &ReturnNotAllowedError{Status: item.OrderStatus}
+		}
+		if remaining := item.Quantity - item.Returned; request.Quantity > remaining {
+			return &ReturnQuantityError{Requested: request.Quantity, Remaining: remaining}
+		}
+
+		request.Status = ReturnRequested
+		request.CreatedAt = time.Now().UTC()
+		id, err := ExecuteInsert(tx, `
+			INSERT INTO return_requests (order_id, order_item_id, quantity, reason, status, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+			request.OrderID, request.OrderItemID, request.Quantity, request.Reason, request.Status, request.CreatedAt)
+		if err != nil {
+			return err
+		}
+		request.ID = id
+		return nil
+	})
+	if err != nil {
+		return ReturnRequest{}, err
+	}
+	return request, nil
+}
+
+func (repo *ReturnRepository) GetById(id int) (ReturnRequest, error) {
+	request, err := QueryStruct[ReturnRequest](repo.dbTemplate, returnSelectQuery+`
+		WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ReturnRequest{}, ErrReturnNotFound
+		}
+		return ReturnRequest{}, err
+	}
+
+	refund, err := QueryStruct[Refund](repo.dbTemplate, `
+		SELECT r.id, r.order_id, r.return_request_id, r.quantity, r.amount, r.tax_amount, r.created_at,
+		       b.id AS "book.id", b.title AS "book.title"
+		FROM refunds r
+		JOIN books b ON r.book_id = b.id
+		WHERE r.return_request_id = $1`, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ReturnRequest{}, err
+	}
+	request.Refund = refund
+	return *request, nil
+}
+
+func (repo *ReturnRepository) GetByOrderId(orderID int) ([]ReturnRequest, error) {
+	return QueryStructs[ReturnRequest](repo.dbTemplate, returnSelectQuery+`
+		WHERE order_id = $1
+		ORDER BY created_at, id`, orderID)
+}
+
+// GetAll lists the return requests with the given status, or all of them when status is empty.
+func (repo *ReturnRepository) GetAll(status string) ([]ReturnRequest, error) {
+	return QueryStructs[ReturnRequest](repo.dbTemplate, returnSelectQuery+`
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at, id`, status)
+}
+
+// lockPendingReturn locks a return request that has not been decided yet.
+func lockPendingReturn(tx *DBTemplate, id int) (ReturnRequest, error) {
+	request, err := QueryStruct[ReturnRequest](tx, returnSelectQuery+`
+		WHERE id = $1
+		FOR UPDATE`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ReturnRequest{}, ErrReturnNotFound
+		}
+		return ReturnRequest{}, err
+	}
+	if request.Status != ReturnRequested {
+		return ReturnRequest{}, ErrReturnAlreadyDecided
+	}
+	return *request, nil
+}
+
+// lockReturnableOrder locks an order, so its status can't change until the transaction ends, and
+// checks its items can still be returned.
+func lockReturnableOrder(tx *DBTemplate, orderID int) error {
+	status, err := QueryStruct[string](tx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+	if !slices.Contains(returnableOrderStatuses, *status) {
+		return &ReturnNotAllowedError{Status: *status}
+	}
+	return nil
+}
+
+// Approve accepts a return: the returned units go back in stock and a refund is recorded.
+// The refund is the share of the line total paid for the returned units. The order must still be
+// returnable, an order refunded since the request was made has already been paid back.
+func (repo *ReturnRepository) Approve(id int, note string) (ReturnRequest, error) {
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		request, err := lockPendingReturn(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := lockReturnableOrder(tx, request.OrderID); err != nil {
+			return err
+		}
+		item, err := lockReturnableItem(tx, request.OrderID, request.OrderItemID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		if _, err := ExecuteUpdateOrDelete(tx, `
+			UPDATE return_requests SET status = $1, decision_note = $2, decided_at = $3
+			WHERE id = $4`, ReturnApproved, note, now, id); err != nil {
+			return err
+		}
+		if _, err := ExecuteUpdateOrDelete(tx, `UPDATE books SET stock = stock + $1 WHERE id = $2`, request.Quantity, item.BookID); err != nil {
+			return err
+		}
+
+		// The customer gets back the tax paid on the returned units along with their net price.
+		share := float64(request.Quantity) / float64(item.Quantity)
+		tax := roundMoney(item.TaxAmount * share)
+		amount := roundMoney(item.LineTotal*share) + tax
+		_, err = ExecuteInsert(tx, `
+			INSERT INTO refunds (order_id, return_request_id, book_id, quantity, amount, tax_amount, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			request.OrderID, id, item.BookID, request.Quantity, amount, tax, now)
+		return err
+	})
+	if err != nil {
+		return ReturnRequest{}, err
+	}
+	return repo.GetById(id)
+}
+
+func (repo *ReturnRepository) Reject(id int, note string) (ReturnRequest, error) {
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		if _, err := lockPendingReturn(tx, id); err != nil {
+			return err
+		}
+		_, err := ExecuteUpdateOrDelete(tx, `
+			UPDATE return_requests SET status = $1, decision_note = $2, decided_at = $3
+			WHERE id = $4`, ReturnRejected, note, time.Now().UTC(), id)
+		return err
+	})
+	if err != nil {
+		return ReturnRequest{}, err
+	}
+	return repo.GetById(id)
+}
+
+// GetRefundsInTimeRange returns the refunds issued in a time range, whatever the status of their order.
+func GetRefundsInTimeRange(start, end time.Time, template *DBTemplate) ([]Refund, error) {
+	query := `
+		SELECT r.id, r.order_id, COALESCE(r.return_request_id, 0) AS return_request_id, r.quantity, r.amount, r.tax_amount, r.created_at,
+		       b.id AS "book.id", b.title AS "book.title", b.price AS "book.price",
+		       COALESCE(p.id, 0) AS "book.publisher.id", COALESCE(p.name, '') AS "book.publisher.name",
+		       COALESCE(p.country, '') AS "book.publisher.country", COALESCE(p.website, '') AS "book.publisher.website"
+		FROM refunds r
+		JOIN books b ON r.book_id = b.id
+		LEFT JOIN publishers p ON b.publisher_id = p.id
+		WHERE r.created_at BETWEEN $1 AND $2`
+	return QueryStructs[Refund](template, query, start, end)
+}
@@ -0,0 +1,202 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrReviewNotVerified = errors.New("only customers who bought the book can review it")
+	ErrReviewExists      = errors.New("customer has already reviewed this book")
+)
+
+type ReviewRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewReviewRepository(dbTemplate *DBTemplate) *ReviewRepository {
+	return &ReviewRepository{
+		dbTemplate: dbTemplate.Repository("review"),
+	}
+}
+
+// refreshBookRating recomputes the aggregate rating of a book from its reviews.
+// It must run in the same transaction as the review change, after lockBook.
+func refreshBookRating(tx *DBTemplate, bookID int) error {
+	query := `
+		UPDATE books SET rating_average = r.average, rating_count = r.count
+		FROM (SELECT COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count
+		      FROM reviews WHERE book_id = $1) r
+		WHERE books.id = $1`
+	_, err := ExecuteUpdateOrDelete(tx, query, bookID)
+	return err
+}
+
+// lockBook serializes concurrent review changes of the same book so the aggregate stays exact.
+func lockBook(tx *DBTemplate, bookID int) error {
+	_, err := QueryStruct[int](tx, `SELECT id FROM books WHERE id = $1 FOR UPDATE`, bookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBookNotFound
+	}
+	return err
+}
+
+func (repo *ReviewRepository) HasPurchased(customerID int, bookID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM order_items oi
+			JOIN orders o ON oi.order_id = o.id
+			WHERE o.customer_id = $1 AND oi.book_id = $2 AND o.status = ANY($3)
+		)`
+	purchased, err := QueryStruct[bool](repo.dbTemplate, query, customerID, bookID, pq.Array(RevenueStatuses))
+	if err != nil {
+		return false, err
+	}
+	return *purchased, nil
+}
+
+func (repo *ReviewRepository) Create(review Review) (Review, error) {
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		if err := lockBook(tx, review.BookID); err != nil {
+			return err
+		}
+
+		purchased, err := NewReviewRepository(tx).HasPurchased(review.CustomerID, review.BookID)
+		if err != nil {
+			return err
+		}
+		if !purchased {
+			return ErrReviewNotVerified
+		}
+
+		exists, err := QueryStruct[bool](tx, `SELECT EXISTS (SELECT 1 FROM reviews WHERE book_id = $1 AND customer_id = $2)`, review.BookID, review.CustomerID)
+		if err != nil {
+			return err
+		}
+		if *exists {
+			return ErrReviewExists
+		}
+
+		review.CreatedAt = time.Now().UTC()
+		review.UpdatedAt = review.CreatedAt
+		query := `
+			INSERT INTO reviews (book_id, customer_id, rating, title, body, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+		id, err := ExecuteInsert(tx, query, review.BookID, review.CustomerID, review.Rating, review.Title, review.Body, review.CreatedAt, review.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		review.ID = id
+		return refreshBookRating(tx, review.BookID)
+	})
+	if err != nil {
+		return Review{}, err
+	}
+	return review, nil
+}
+
+func (repo *ReviewRepository) GetById(id int) (Review, error) {
+	query := `
+		SELECT id, book_id, customer_id, rating, title, body, created_at, updated_at
+		FROM reviews
+		WHERE id = $1`
+	review, err := QueryStruct[Review](repo.dbTemplate, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Review{}, errors.New("review not found")
+		}
+		return Review{}, err
+	}
+	return *review, nil
+}
+
+// Update only changes the rating and text, a review cannot move to another book or customer.
+func (repo *ReviewRepository) Update(id int, updated Review) (Review, error) {
+	var review Review
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		existing, err := NewReviewRepository(tx).GetById(id)
+		if err != nil {
+			return err
+		}
+		if err := lockBook(tx, existing.BookID); err != nil {
+			return err
+		}
+
+		existing.Rating = updated.Rating
+		existing.Title = updated.Title
+		existing.Body = updated.Body
+		existing.UpdatedAt = time.Now().UTC()
+		query := `
+			UPDATE reviews SET rating = $1, title = $2, body = $3, updated_at = $4
+			WHERE id = $5`
+		if _, err := ExecuteUpdateOrDelete(tx, query, existing.Rating, existing.Title, existing.Body, existing.UpdatedAt, id); err != nil {
+			return err
+		}
+		review = existing
+		return refreshBookRating(tx, existing.BookID)
+	})
+	if err != nil {
+		return Review{}, err
+	}
+	return review, nil
+}
+
+func (repo *ReviewRepository) Delete(id int) error {
+	return InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		existing, err := NewReviewRepository(tx).GetById(id)
+		if err != nil {
+			return err
+		}
+		if err := lockBook(tx, existing.BookID); err != nil {
+			return err
+		}
+		if _, err := ExecuteUpdateOrDelete(tx, `DELETE FROM reviews WHERE id = $1`, id); err != nil {
+			return err
+		}
+		return refreshBookRating(tx, existing.BookID)
+	})
+}
+
+func (repo *ReviewRepository) GetAll() ([]Review, error) {
+	query := `
+		SELECT id, book_id, customer_id, rating, title, body, created_at, updated_at
+		FROM reviews`
+	reviews, err := QueryStructs[Review](repo.dbTemplate, query)
+	if err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// GetByBookId returns one page of the reviews of a book, newest first. Pages start at 1.
+func (repo *ReviewRepository) GetByBookId(bookID int, page int, pageSize int) (ReviewPage, error) {
+	total, err := QueryStruct[int](repo.dbTemplate, `SELECT COUNT(*) FROM reviews WHERE book_id = $1`, bookID)
+	if err != nil {
+		return ReviewPage{}, err
+	}
+
+	query := `
+		SELECT id, book_id, customer_id, rating, title, body, created_at, updated_at
+		FROM reviews
+		WHERE book_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+	reviews, err := QueryStructs[Review](repo.dbTemplate, query, bookID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return ReviewPage{}, err
+	}
+	if reviews == nil {
+		reviews = []Review{}
+	}
+
+	return ReviewPage{
+		Reviews:  reviews,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    *total,
+	}, nil
+}
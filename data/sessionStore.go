@@ -0,0 +1,322 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SessionIdleTimeout     = 24 * time.Hour
+	SessionAbsoluteTimeout = 7 * 24 * time.Hour
+	// lastSeenResolution limits how often a session is written to when it is used.
+	lastSeenResolution = time.Minute
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionInactive = errors.New("session has expired or was revoked")
+)
+
+// SessionStore keeps the login sessions. Every authenticated request touches its session, so
+// revoked or timed out sessions are refused even while their access tokens are unexpired.
+type SessionStore interface {
+	Create(session Session) error
+	// Touch checks that a session is active and records its use.
+	Touch(id string, clientIP string, now time.Time) (Session, error)
+	Get(id string) (Session, error)
+	Revoke(id string, now time.Time) error
+	// RevokeUser revokes the sessions of a user except keepID, which may be empty.
+	RevokeUser(userID int, keepID string, now time.Time) (int, error)
+	ListActive(userID int, now time.Time) ([]Session, error)
+	// CountActive counts the active sessions of all users.
+	CountActive(now time.Time) (int, error)
+	DeleteInactive(now time.Time) (int, error)
+}
+
+// NewSession starts a session for a user, with its absolute timeout.
+func NewSession(id string, userID int, clientIP string, userAgent string, now time.Time) Session {
+	return Session{
+		ID:         id,
+		UserID:     userID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(SessionAbsoluteTimeout),
+		ClientIP:   clientIP,
+		UserAgent:  userAgent,
+	}
+}
+
+func (session Session) active(now time.Time) bool {
+	return session.RevokedAt == nil && now.Before(session.ExpiresAt) && now.Before(session.LastSeenAt.Add(SessionIdleTimeout))
+}
+
+func (session Session) withIdleExpiry() Session {
+	session.IdleExpiresAt = session.LastSeenAt.Add(SessionIdleTimeout)
+	if session.ExpiresAt.Before(session.IdleExpiresAt) {
+		session.IdleExpiresAt = session.ExpiresAt
+	}
+	return session
+}
+
+// NewSessionStore returns the Postgres session store, or an in-memory store when the database
+// can't be reached. Sessions kept in memory are lost on restart and not shared between instances.
+func NewSessionStore(template *DBTemplate) SessionStore {
+	if err := template.Ping(); err != nil {
+		log.Printf("Database unreachable, keeping sessions in memory: %v", err)
+		return NewMemorySessionStore()
+	}
+	return NewPostgresSessionStore(template)
+}
+
+// StartSessionJanitor periodically removes the sessions that timed out or were revoked.
+func StartSessionJanitor(store SessionStore) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := store.DeleteInactive(time.Now().UTC()); err != nil {
+			log.Println("Error removing inactive sessions:", err)
+		}
+	}
+}
+
+type PostgresSessionStore struct {
+	dbTemplate *DBTemplate
+}
+
+func NewPostgresSessionStore(dbTemplate *DBTemplate) *PostgresSessionStore {
+	return &PostgresSessionStore{
+		dbTemplate: dbTemplate.Repository("session"),
+	}
+}
+
+const sessionSelectQuery = `
+		SELECT id, user_id, created_at, last_seen_at, expires_at, client_ip, user_agent, revoked_at
+		FROM sessions`
+
+func (store *PostgresSessionStore) Create(session Session) error {
+	_, err := ExecuteUpdateOrDelete(store.dbTemplate, `
+		INSERT INTO sessions (id, user_id, created_at, last_seen_at, expires_at, client_ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, session.ID, session.UserID, session.CreatedAt, session.LastSeenAt,
+		session.ExpiresAt, session.ClientIP, session.UserAgent)
+	return err
+}
+
+// parseSessionID parses a session ID so it is compared as a UUID and the primary key index is used.
+// A malformed ID can't belong to any session.
+func parseSessionID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.UUID{}, ErrSessionNotFound
+	}
+	return parsed, nil
+}
+
+func (store *PostgresSessionStore) Get(id string) (Session, error) {
+	sessionID, err := parseSessionID(id)
+	if err != nil {
+		return Session{}, err
+	}
+	session, err := QueryStruct[Session](store.dbTemplate, sessionSelectQuery+`
+		WHERE id = $1`, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Session{}, ErrSessionNotFound
+		}
+		return Session{}, err
+	}
+	return session.withIdleExpiry(), nil
+}
+
+func (store *PostgresSessionStore) Touch(id string, clientIP string, now time.Time) (Session, error) {
+	session, err := store.Get(id)
+	if err != nil {
+		return Session{}, err
+	}
+	if !session.active(now) {
+		return Session{}, ErrSessionInactive
+	}
+	if now.Sub(session.LastSeenAt) < lastSeenResolution && session.ClientIP == clientIP {
+		return session, nil
+	}
+
+	session.LastSeenAt = now
+	session.ClientIP = clientIP
+	if _, err := ExecuteUpdateOrDelete(store.dbTemplate, `
+		UPDATE sessions SET last_seen_at = $1, client_ip = $2
+		WHERE id = $3`, session.LastSeenAt, session.ClientIP, session.ID); err != nil {
+		return Session{}, err
+	}
+	return session.withIdleExpiry(), nil
+}
+
+func (store *PostgresSessionStore) Revoke(id string, now time.Time) error {
+	sessionID, err := parseSessionID(id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := ExecuteUpdateOrDelete(store.dbTemplate, `
+		UPDATE sessions SET revoked_at = COALESCE(revoked_at, $1)
+		WHERE id = $2`, now, sessionID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (store *PostgresSessionStore) RevokeUser(userID int, keepID string, now time.Time) (int, error) {
+	// No session is kept when keepID is empty or malformed.
+	var keep uuid.NullUUID
+	if parsed, err := parseSessionID(keepID); err == nil {
+		keep = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+	return ExecuteUpdateOrDelete(store.dbTemplate, `
+		UPDATE sessions SET revoked_at = $1
+		WHERE user_id = $2 AND ($3::uuid IS NULL OR id <> $3) AND revoked_at IS NULL`, now, userID, keep)
+}
+
+func (store *PostgresSessionStore) ListActive(userID int, now time.Time) ([]Session, error) {
+	sessions, err := QueryStructs[Session](store.dbTemplate, sessionSelectQuery+`
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2 AND last_seen_at > $3
+		ORDER BY last_seen_at DESC`, userID, now, now.Add(-SessionIdleTimeout))
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i] = sessions[i].withIdleExpiry()
+	}
+	return sessions, nil
+}
+
+func (store *PostgresSessionStore) CountActive(now time.Time) (int, error) {
+	count, err := QueryStruct[int](store.dbTemplate, `
+		SELECT COUNT(*) FROM sessions
+		WHERE revoked_at IS NULL AND expires_at > $1 AND last_seen_at > $2`, now, now.Add(-SessionIdleTimeout))
+	if err != nil {
+		return 0, err
+	}
+	return *count, nil
+}
+
+func (store *PostgresSessionStore) DeleteInactive(now time.Time) (int, error) {
+	return ExecuteUpdateOrDelete(store.dbTemplate, `
+		DELETE FROM sessions
+		WHERE revoked_at IS NOT NULL OR expires_at <= $1 OR last_seen_at <= $2`, now, now.Add(-SessionIdleTimeout))
+}
+
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]Session)}
+}
+
+func (store *MemorySessionStore) Create(session Session) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.sessions[session.ID] = session
+	return nil
+}
+
+func (store *MemorySessionStore) Get(id string) (Session, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	session, ok := store.sessions[id]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return session.withIdleExpiry(), nil
+}
+
+func (store *MemorySessionStore) Touch(id string, clientIP string, now time.Time) (Session, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	session, ok := store.sessions[id]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	if !session.active(now) {
+		return Session{}, ErrSessionInactive
+	}
+	session.LastSeenAt = now
+	session.ClientIP = clientIP
+	store.sessions[id] = session
+	return session.withIdleExpiry(), nil
+}
+
+func (store *MemorySessionStore) Revoke(id string, now time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	session, ok := store.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt == nil {
+		session.RevokedAt = &now
+		store.sessions[id] = session
+	}
+	return nil
+}
+
+func (store *MemorySessionStore) RevokeUser(userID int, keepID string, now time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	revoked := 0
+	for id, session := range store.sessions {
+		if session.UserID == userID && id != keepID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			store.sessions[id] = session
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (store *MemorySessionStore) ListActive(userID int, now time.Time) ([]Session, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	sessions := []Session{}
+	for _, session := range store.sessions {
+		if session.UserID == userID && session.active(now) {
+			sessions = append(sessions, session.withIdleExpiry())
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+func (store *MemorySessionStore) CountActive(now time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	count := 0
+	for _, session := range store.sessions {
+		if session.active(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (store *MemorySessionStore) DeleteInactive(now time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	removed := 0
+	for id, session := range store.sessions {
+		if !session.active(now) {
+			delete(store.sessions, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
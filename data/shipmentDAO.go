@@ -0,0 +1,296 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	ShipmentLabelCreated   = "label_created"
+	ShipmentInTransit      = "in_transit"
+	ShipmentOutForDelivery = "out_for_delivery"
+	ShipmentDelivered      = "delivered"
+	ShipmentException      = "exception"
+)
+
+var ShipmentStatuses = []string{ShipmentLabelCreated, ShipmentInTransit, ShipmentOutForDelivery, ShipmentDelivered, ShipmentException}
+
+// Goods can only leave the warehouse once paid, later shipments of a partially shipped order are allowed.
+var shippableOrderStatuses = []string{OrderPaid, OrderShipped}
+
+var (
+	ErrShipmentNotFound       = errors.New("shipment not found")
+	ErrShipmentDelivered      = errors.New("shipment has already been delivered")
+	ErrUnknownShipmentStatus  = errors.New("unknown shipment status")
+	ErrNothingToShip          = errors.New("every item of the order has already been shipped")
+	ErrShipmentTrackingNeeded = errors.New("carrier and tracking number are required")
+)
+
+type ShipmentNotAllowedError struct {
+	Status string
+}
+
+func (e *ShipmentNotAllowedError) Error() string {
+	return fmt.Sprintf("a %s order cannot be shipped", e.Status)
+}
+
+type ShipmentQuantityError struct {
+	OrderItemID int
+	Requested   int
+	Remaining   int
+}
+
+func (e *ShipmentQuantityError) Error() string {
+	return fmt.Sprintf("cannot ship %d units of order item %d, only %d left to ship", e.Requested, e.OrderItemID, e.Remaining)
+}
+
+type shippableItem struct {
+	ID       int `db:"id"`
+	Quantity int `db:"quantity"`
+	Shipped  int `db:"shipped"`
+}
+
+const shipmentSelectQuery = `
+		SELECT id, order_id, carrier, tracking_number, status, created_at
+		FROM shipments`
+
+type ShipmentRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewShipmentRepository(dbTemplate *DBTemplate) *ShipmentRepository {
+	return &ShipmentRepository{
+		dbTemplate: dbTemplate.Repository("shipment"),
+	}
+}
+
+// shippableItems returns the items of an order with the quantity already shipped, the order row must be locked.
+func shippableItems(tx *DBTemplate, orderID int) ([]shippableItem, error) {
+	return QueryStructs[shippableItem](tx, `
+		SELECT oi.id, oi.quantity, COALESCE(SUM(si.quantity), 0) AS shipped
+		FROM order_items oi
+		LEFT JOIN shipment_items si ON si.order_item_id = oi.id
+		WHERE oi.order_id = $1
+		GROUP BY oi.id, oi.quantity
+		ORDER BY oi.id`, orderID)
+}
+
+func recordShipmentEvent(tx *DBTemplate, shipmentID int, status string, note string, at time.Time) error {
+	_, err := ExecuteInsert(tx, `
+		INSERT INTO shipment_events (shipment_id, status, note, occurred_at)
+		VALUES ($1, $2, $3, $4) RETURNING id`, shipmentID, status, note, at)
+	return err
+}
+
+// Create ships some items of an order, or everything not shipped yet when no item is listed.
+// The first shipment of a paid order moves it to shipped.
+func (repo *ShipmentRepository) Create(shipment Shipment) (Shipment, error) {
+	if shipment.Carrier == "" || shipment.TrackingNumber == "" {
+		return Shipment{}, ErrShipmentTrackingNeeded
+	}
+
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		// Locking the order serializes the shipments of an order and its status changes.
+		status, err := QueryStruct[string](tx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, shipment.OrderID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if !slices.Contains(shippableOrderStatuses, *status) {
+			return &ShipmentNotAllowedError{Status: *status}
+		}
+
+		items, err := shippableItems(tx, shipment.OrderID)
+		if err != nil {
+			return err
+		}
+		if len(shipment.Items) == 0 {
+			for _, item := range items {
+				if remaining := item.Quantity - item.Shipped; remaining > 0 {
+					shipment.Items = append(shipment.Items, ShipmentItem{OrderItemID: item.ID, Quantity: remaining})
+				}
+			}
+			if len(shipment.Items) == 0 {
+				return ErrNothingToShip
+			}
+		}
+
+		// The same order item may be listed more than once, quantities are summed.
+		quantities := make(map[int]int)
+		for _, shipped := range shipment.Items {
+			quantities[shipped.OrderItemID] += shipped.Quantity
+		}
+		for orderItemID, quantity := range quantities {
+			i := slices.IndexFunc(items, func(item shippableItem) bool { return item.ID == orderItemID })
+			if i < 0 {
+				return ErrOrderItemNotFound
+			}
+			if remaining := items[i].Quantity - items[i].Shipped; quantity > remaining {
+				return &ShipmentQuantityError{OrderItemID: orderItemID, Requested: quantity, Remaining: remaining}
+			}
+		}
+
+		shipment.Status = ShipmentLabelCreated
+		shipment.CreatedAt = time.Now().UTC()
+		id, err := ExecuteInsert(tx, `
+			INSERT INTO shipments (order_id, carrier, tracking_number, status, created_at)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			shipment.OrderID, shipment.Carrier, shipment.TrackingNumber, shipment.Status, shipment.CreatedAt)
+		if err != nil {
+			return err
+		}
+		shipment.ID = id
+
+		for orderItemID, quantity := range quantities {
+			if _, err := ExecuteUpdateOrDelete(tx, `
+				INSERT INTO shipment_items (shipment_id, order_item_id, quantity)
+				VALUES ($1, $2, $3)`, id, orderItemID, quantity); err != nil {
+				return err
+			}
+		}
+		if err := recordShipmentEvent(tx, id, shipment.Status, "", shipment.CreatedAt); err != nil {
+			return err
+		}
+
+		if *status == OrderPaid {
+			note := fmt.Sprintf("shipment #%d", id)
+			if _, err := NewOrderRepository(tx).Transition(shipment.OrderID, OrderShipped, note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Shipment{}, err
+	}
+	return repo.GetById(shipment.ID)
+}
+
+func (repo *ShipmentRepository) loadDetails(shipments []Shipment) error {
+	for i := range shipments {
+		items, err := QueryStructs[ShipmentItem](repo.dbTemplate, `
+			SELECT si.order_item_id, b.id AS book_id, b.title, si.quantity
+			FROM shipment_items si
+			JOIN order_items oi ON si.order_item_id = oi.id
+			JOIN books b ON oi.book_id = b.id
+			WHERE si.shipment_id = $1
+			ORDER BY si.order_item_id`, shipments[i].ID)
+		if err != nil {
+			return err
+		}
+		events, err := QueryStructs[ShipmentEvent](repo.dbTemplate, `
+			SELECT status, note, occurred_at
+			FROM shipment_events
+			WHERE shipment_id = $1
+			ORDER BY occurred_at, id`, shipments[i].ID)
+		if err != nil {
+			return err
+		}
+		shipments[i].Items = items
+		shipments[i].Events = events
+	}
+	return nil
+}
+
+func (repo *ShipmentRepository) GetById(id int) (Shipment, error) {
+	shipment, err := QueryStruct[Shipment](repo.dbTemplate, shipmentSelectQuery+`
+		WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Shipment{}, ErrShipmentNotFound
+		}
+		return Shipment{}, err
+	}
+	shipments := []Shipment{*shipment}
+	if err := repo.loadDetails(shipments); err != nil {
+		return Shipment{}, err
+	}
+	return shipments[0], nil
+}
+
+func (repo *ShipmentRepository) GetByOrderId(orderID int) ([]Shipment, error) {
+	shipments, err := QueryStructs[Shipment](repo.dbTemplate, shipmentSelectQuery+`
+		WHERE order_id = $1
+		ORDER BY created_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.loadDetails(shipments); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// AddEvent records a tracking update and moves the shipment to its status. Once every item of the
+// order is shipped and every shipment delivered, the order itself is marked delivered.
+func (repo *ShipmentRepository) AddEvent(id int, event ShipmentEvent) (Shipment, error) {
+	if !slices.Contains(ShipmentStatuses, event.Status) {
+		return Shipment{}, ErrUnknownShipmentStatus
+	}
+
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		orderID, err := QueryStruct[int](tx, `SELECT order_id FROM shipments WHERE id = $1`, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrShipmentNotFound
+			}
+			return err
+		}
+		// The order is locked before the shipment, in the same order as Create, so the two cannot deadlock.
+		orderStatus, err := QueryStruct[string](tx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, *orderID)
+		if err != nil {
+			return err
+		}
+		shipment, err := QueryStruct[Shipment](tx, shipmentSelectQuery+`
+			WHERE id = $1
+			FOR UPDATE`, id)
+		if err != nil {
+			return err
+		}
+		if shipment.Status == ShipmentDelivered {
+			return ErrShipmentDelivered
+		}
+
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now().UTC()
+		}
+		if _, err := ExecuteUpdateOrDelete(tx, `UPDATE shipments SET status = $1 WHERE id = $2`, event.Status, id); err != nil {
+			return err
+		}
+		if err := recordShipmentEvent(tx, id, event.Status, event.Note, event.OccurredAt); err != nil {
+			return err
+		}
+
+		if event.Status != ShipmentDelivered || *orderStatus != OrderShipped {
+			return nil
+		}
+		items, err := shippableItems(tx, shipment.OrderID)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if item.Shipped < item.Quantity {
+				return nil
+			}
+		}
+		undelivered, err := QueryStruct[int](tx, `
+			SELECT COUNT(*) FROM shipments WHERE order_id = $1 AND status <> $2`, shipment.OrderID, ShipmentDelivered)
+		if err != nil {
+			return err
+		}
+		if *undelivered > 0 {
+			return nil
+		}
+		_, err = NewOrderRepository(tx).Transition(shipment.OrderID, OrderDelivered, "all shipments delivered")
+		return err
+	})
+	if err != nil {
+		return Shipment{}, err
+	}
+	return repo.GetById(id)
+}
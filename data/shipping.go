@@ -0,0 +1,77 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	ShippingByWeight = "weight"
+	ShippingByItems  = "items"
+
+	// AnyCountry in the countries of a zone makes it the fallback for countries no other zone lists.
+	AnyCountry = "*"
+)
+
+// ShippingUnavailableError is returned at checkout when no shipping zone serves the customer country.
+type ShippingUnavailableError struct {
+	Country string
+}
+
+func (e *ShippingUnavailableError) Error() string {
+	if e.Country == "" {
+		return "the customer has no shipping address"
+	}
+	return fmt.Sprintf("no shipping available to %s", e.Country)
+}
+
+// shippingZoneFor returns the zone listing the country, or the fallback zone when none does.
+func shippingZoneFor(zones []ShippingZone, country string) (ShippingZone, bool) {
+	country = strings.TrimSpace(country)
+	fallback, hasFallback := ShippingZone{}, false
+	for _, zone := range zones {
+		for _, zoneCountry := range zone.Countries {
+			if strings.EqualFold(zoneCountry, country) {
+				return zone, true
+			}
+			if zoneCountry == AnyCountry && !hasFallback {
+				fallback, hasFallback = zone, true
+			}
+		}
+	}
+	return fallback, hasFallback
+}
+
+// shippingCost prices the delivery of the order items in a zone. Orders whose discounted merchandise
+// reaches the free shipping threshold of the zone ship for free.
+func shippingCost(zone ShippingZone, items []OrderItem) float64 {
+	merchandise, units, grams := 0.0, 0, 0
+	for _, item := range items {
+		merchandise += item.UnitPrice*float64(item.Quantity) - item.DiscountAmount
+		units += item.Quantity
+		grams += item.Book.WeightGrams * item.Quantity
+	}
+	if zone.FreeShippingThreshold > 0 && roundMoney(merchandise) >= zone.FreeShippingThreshold {
+		return 0
+	}
+
+	if zone.RateBasis == ShippingByWeight {
+		return roundMoney(zone.BaseRate + zone.UnitRate*math.Ceil(float64(grams)/1000))
+	}
+	return roundMoney(zone.BaseRate + zone.UnitRate*float64(units))
+}
+
+// applyShipping sets the shipping charge of the order from the zone of the customer address.
+func applyShipping(tx *DBTemplate, order *Order) error {
+	zones, err := NewShippingZoneRepository(tx).GetAll()
+	if err != nil {
+		return err
+	}
+	zone, found := shippingZoneFor(zones, order.Customer.Address.Country)
+	if !found || order.Customer.Address.Country == "" {
+		return &ShippingUnavailableError{Country: order.Customer.Address.Country}
+	}
+	order.ShippingTotal = shippingCost(zone, order.Items)
+	return nil
+}
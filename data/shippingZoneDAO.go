@@ -0,0 +1,88 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrShippingZoneNotFound = errors.New("shipping zone not found")
+	ErrShippingZoneExists   = errors.New("a shipping zone with this name already exists")
+)
+
+const shippingZoneSelectQuery = `
+		SELECT id, name, countries, rate_basis, base_rate, unit_rate, free_shipping_threshold
+		FROM shipping_zones`
+
+type ShippingZoneRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewShippingZoneRepository(dbTemplate *DBTemplate) *ShippingZoneRepository {
+	return &ShippingZoneRepository{
+		dbTemplate: dbTemplate.Repository("shipping_zone"),
+	}
+}
+
+func (repo *ShippingZoneRepository) Create(zone ShippingZone) (ShippingZone, error) {
+	query := `
+		INSERT INTO shipping_zones (name, countries, rate_basis, base_rate, unit_rate, free_shipping_threshold)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, zone.Name, zone.Countries, zone.RateBasis, zone.BaseRate, zone.UnitRate, zone.FreeShippingThreshold)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ShippingZone{}, ErrShippingZoneExists
+		}
+		return ShippingZone{}, err
+	}
+	zone.ID = id
+	return zone, nil
+}
+
+func (repo *ShippingZoneRepository) GetById(id int) (ShippingZone, error) {
+	zone, err := QueryStruct[ShippingZone](repo.dbTemplate, shippingZoneSelectQuery+`
+		WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ShippingZone{}, ErrShippingZoneNotFound
+		}
+		return ShippingZone{}, err
+	}
+	return *zone, nil
+}
+
+func (repo *ShippingZoneRepository) Update(id int, updated ShippingZone) (ShippingZone, error) {
+	query := `
+		UPDATE shipping_zones SET name = $1, countries = $2, rate_basis = $3, base_rate = $4, unit_rate = $5,
+		                          free_shipping_threshold = $6
+		WHERE id = $7`
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, updated.Name, updated.Countries, updated.RateBasis, updated.BaseRate, updated.UnitRate,
+		updated.FreeShippingThreshold, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ShippingZone{}, ErrShippingZoneExists
+		}
+		return ShippingZone{}, err
+	}
+	if rowsAffected == 0 {
+		return ShippingZone{}, ErrShippingZoneNotFound
+	}
+	updated.ID = id
+	return updated, nil
+}
+
+func (repo *ShippingZoneRepository) Delete(id int) error {
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, `DELETE FROM shipping_zones WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrShippingZoneNotFound
+	}
+	return nil
+}
+
+func (repo *ShippingZoneRepository) GetAll() ([]ShippingZone, error) {
+	return QueryStructs[ShippingZone](repo.dbTemplate, shippingZoneSelectQuery+`
+		ORDER BY name`)
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"time"
+)
+
+type SigningKeyRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewSigningKeyRepository(dbTemplate *DBTemplate) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		dbTemplate: dbTemplate.Repository("signing_key"),
+	}
+}
+
+// GetPublished returns the keys whose tokens may still be valid: the active keys, and the keys
+// retired after retiredSince. The most recent key comes first.
+func (repo *SigningKeyRepository) GetPublished(retiredSince time.Time) ([]SigningKey, error) {
+	return QueryStructs[SigningKey](repo.dbTemplate, `
+		SELECT kid, private_key, created_at, retired_at
+		FROM signing_keys
+		WHERE retired_at IS NULL OR retired_at > $1
+		ORDER BY created_at DESC`, retiredSince)
+}
+
+// Rotate stores a new key and retires the previous ones in the same transaction.
+func (repo *SigningKeyRepository) Rotate(key SigningKey) error {
+	return InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		if _, err := ExecuteUpdateOrDelete(tx, `
+			UPDATE signing_keys SET retired_at = $1
+			WHERE retired_at IS NULL`, key.CreatedAt); err != nil {
+			return err
+		}
+		_, err := ExecuteUpdateOrDelete(tx, `
+			INSERT INTO signing_keys (kid, private_key, created_at)
+			VALUES ($1, $2, $3)`, key.Kid, key.PrivateKey, key.CreatedAt)
+		return err
+	})
+}
+
+// DeleteRetiredBefore removes the keys that were retired before a given time.
+func (repo *SigningKeyRepository) DeleteRetiredBefore(before time.Time) (int, error) {
+	return ExecuteUpdateOrDelete(repo.dbTemplate, `DELETE FROM signing_keys WHERE retired_at <= $1`, before)
+}
@@ -2,6 +2,8 @@ package data
 
 import (
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Author struct {
@@ -11,16 +13,75 @@ type Author struct {
 	Bio       string `json:"bio" db:"bio"`
 }
 
+type Publisher struct {
+	ID      int    `json:"id" db:"id"`
+	Name    string `json:"name" db:"name"`
+	Country string `json:"country" db:"country"`
+	Website string `json:"website" db:"website"`
+}
 
 type Book struct {
-	ID          int       `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Author      Author    `json:"author" db:"author"`
-	TextGenres  string    `json:"-" db:"genres"` 
-	PublishedAt time.Time `json:"published_at" db:"published_at"`
-	Price       float64   `json:"price" db:"price"`
-	Stock       int       `json:"stock" db:"stock"`
-	Genres      []string  `json:"genres" db:"-"`
+	ID            int       `json:"id" db:"id"`
+	Title         string    `json:"title" db:"title"`
+	Author        Author    `json:"author" db:"author"`
+	Publisher     Publisher `json:"publisher" db:"publisher"`
+	TextGenres    string    `json:"-" db:"genres"`
+	PublishedAt   time.Time `json:"published_at" db:"published_at"`
+	Price         float64   `json:"price" db:"price"`
+	Stock         int       `json:"stock" db:"stock"`
+	Genres        []string  `json:"genres" db:"-"`
+	AverageRating float64   `json:"average_rating" db:"rating_average"`
+	RatingCount   int       `json:"rating_count" db:"rating_count"`
+	TaxCategory   string    `json:"tax_category" db:"tax_category"`
+	WeightGrams   int       `json:"weight_grams" db:"weight_grams"`
+}
+
+type BookCover struct {
+	BookID               int       `json:"book_id" db:"book_id"`
+	ContentType          string    `json:"content_type" db:"content_type"`
+	Size                 int64     `json:"size_bytes" db:"size_bytes"`
+	Width                int       `json:"width" db:"width"`
+	Height               int       `json:"height" db:"height"`
+	ETag                 string    `json:"etag" db:"etag"`
+	ThumbnailContentType string    `json:"thumbnail_content_type" db:"thumbnail_content_type"`
+	ThumbnailSize        int64     `json:"thumbnail_size_bytes" db:"thumbnail_size_bytes"`
+	ThumbnailETag        string    `json:"thumbnail_etag" db:"thumbnail_etag"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Review struct {
+	ID         int       `json:"id" db:"id"`
+	BookID     int       `json:"book_id" db:"book_id"`
+	CustomerID int       `json:"customer_id" db:"customer_id"`
+	Rating     int       `json:"rating" db:"rating"`
+	Title      string    `json:"title" db:"title"`
+	Body       string    `json:"body" db:"body"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type ReviewPage struct {
+	Reviews  []Review `json:"reviews"`
+	Page     int      `json:"page"`
+	PageSize int      `json:"page_size"`
+	Total    int      `json:"total"`
+}
+
+type CartLine struct {
+	Book      Book    `json:"book" db:"book"`
+	Quantity  int     `json:"quantity" db:"quantity"`
+	UnitPrice float64 `json:"unit_price" db:"-"`
+	LineTotal float64 `json:"line_total" db:"-"`
+	InStock   bool    `json:"in_stock" db:"-"`
+}
+
+type Cart struct {
+	Lines             []CartLine `json:"lines"`
+	ItemCount         int        `json:"item_count"`
+	Subtotal          float64    `json:"subtotal"`
+	OutOfStockBookIDs []int      `json:"out_of_stock_book_ids"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
 }
 
 type Address struct {
@@ -31,19 +92,274 @@ type Address struct {
 	Country    string `json:"country" db:"country"`
 }
 
+// OrderItem.LineTotal is the net amount of the line, after discounts and before tax.
 type OrderItem struct {
-	ID       int   `json:"id" db:"id"`
-	Book     Book  `json:"book" db:"book"`
-	Quantity int   `json:"quantity" db:"quantity"`
+	ID             int     `json:"id" db:"id"`
+	Book           Book    `json:"book" db:"book"`
+	Quantity       int     `json:"quantity" db:"quantity"`
+	UnitPrice      float64 `json:"unit_price" db:"unit_price"`
+	DiscountAmount float64 `json:"discount_amount" db:"discount_amount"`
+	LineTotal      float64 `json:"line_total" db:"line_total"`
+	TaxRate        float64 `json:"tax_rate" db:"tax_rate"`
+	TaxAmount      float64 `json:"tax_amount" db:"tax_amount"`
+	GrossTotal     float64 `json:"gross_total" db:"gross_total"`
 }
 
 type Order struct {
-	ID         int         `json:"id" db:"id"`
-	Customer   Customer    `json:"customer" db:"customer"`
-	Items      []OrderItem `json:"items" db:"items"`
-	TotalPrice float64     `json:"total_price" db:"total_price"`
-	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
-	Status     string      `json:"status" db:"status"`
+	ID            int                 `json:"id" db:"id"`
+	Customer      Customer            `json:"customer" db:"customer"`
+	Items         []OrderItem         `json:"items" db:"items"`
+	Subtotal      float64             `json:"subtotal" db:"subtotal"`
+	DiscountTotal float64             `json:"discount_total" db:"discount_total"`
+	TaxTotal      float64             `json:"tax_total" db:"tax_total"`
+	ShippingTotal float64             `json:"shipping_total" db:"shipping_total"`
+	NetTotal      float64             `json:"net_total" db:"net_total"`
+	TotalPrice    float64             `json:"total_price" db:"total_price"`
+	CreatedAt     time.Time           `json:"created_at" db:"created_at"`
+	Status        string              `json:"status" db:"status"`
+	StatusHistory []OrderStatusChange `json:"status_history" db:"-"`
+	Promotions    []AppliedPromotion  `json:"promotions" db:"-"`
+	TaxBreakdown  []TaxSummary        `json:"tax_breakdown" db:"-"`
+	// SkippedPromotions explains why promotion codes sent at checkout did not apply.
+	SkippedPromotions []SkippedPromotion `json:"skipped_promotions,omitempty" db:"-"`
+}
+
+type Promotion struct {
+	ID               int        `json:"id" db:"id"`
+	Name             string     `json:"name" db:"name"`
+	Code             string     `json:"code,omitempty" db:"code"`
+	Kind             string     `json:"kind" db:"kind"`
+	Value            float64    `json:"value" db:"value"`
+	Scope            string     `json:"scope" db:"scope"`
+	ScopeValue       string     `json:"scope_value,omitempty" db:"scope_value"`
+	MinSpend         float64    `json:"min_spend" db:"min_spend"`
+	BuyQuantity      int        `json:"buy_quantity,omitempty" db:"buy_quantity"`
+	GetQuantity      int        `json:"get_quantity,omitempty" db:"get_quantity"`
+	UsageLimit       int        `json:"usage_limit" db:"usage_limit"`
+	PerCustomerLimit int        `json:"per_customer_limit" db:"per_customer_limit"`
+	StartsAt         *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt           *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	Active           bool       `json:"active" db:"active"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+type AppliedPromotion struct {
+	PromotionID int     `json:"promotion_id" db:"promotion_id"`
+	Name        string  `json:"name" db:"name"`
+	Code        string  `json:"code,omitempty" db:"code"`
+	Amount      float64 `json:"amount" db:"amount"`
+	Explanation string  `json:"explanation" db:"explanation"`
+}
+
+type SkippedPromotion struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// TaxRule.State and TaxRule.Category are left empty for rules that apply to the whole country or to every category.
+type TaxRule struct {
+	ID       int     `json:"id" db:"id"`
+	Name     string  `json:"name" db:"name"`
+	Country  string  `json:"country" db:"country"`
+	State    string  `json:"state,omitempty" db:"state"`
+	Category string  `json:"category,omitempty" db:"category"`
+	Rate     float64 `json:"rate" db:"rate"`
+}
+
+// ShippingZone.Countries may hold "*" for a zone that serves every country not listed in another zone.
+// ShippingZone.UnitRate is charged per started kilogram or per item, depending on ShippingZone.RateBasis.
+type ShippingZone struct {
+	ID                    int            `json:"id" db:"id"`
+	Name                  string         `json:"name" db:"name"`
+	Countries             pq.StringArray `json:"countries" db:"countries"`
+	RateBasis             string         `json:"rate_basis" db:"rate_basis"`
+	BaseRate              float64        `json:"base_rate" db:"base_rate"`
+	UnitRate              float64        `json:"unit_rate" db:"unit_rate"`
+	FreeShippingThreshold float64        `json:"free_shipping_threshold" db:"free_shipping_threshold"`
+}
+
+type Shipment struct {
+	ID             int             `json:"id" db:"id"`
+	OrderID        int             `json:"order_id" db:"order_id"`
+	Carrier        string          `json:"carrier" db:"carrier"`
+	TrackingNumber string          `json:"tracking_number" db:"tracking_number"`
+	Status         string          `json:"status" db:"status"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	Items          []ShipmentItem  `json:"items" db:"-"`
+	Events         []ShipmentEvent `json:"events" db:"-"`
+}
+
+type ShipmentItem struct {
+	OrderItemID int    `json:"order_item_id" db:"order_item_id"`
+	BookID      int    `json:"book_id" db:"book_id"`
+	Title       string `json:"title" db:"title"`
+	Quantity    int    `json:"quantity" db:"quantity"`
+}
+
+type ShipmentEvent struct {
+	Status     string    `json:"status" db:"status"`
+	Note       string    `json:"note,omitempty" db:"note"`
+	OccurredAt time.Time `json:"occurred_at" db:"occurred_at"`
+}
+
+// Invoice is the snapshot of an order taken when the invoice is issued. It is stored as is and
+// never recomputed, so later changes to books, customers or tax rules do not alter issued invoices.
+type Invoice struct {
+	Number        string        `json:"number"`
+	OrderID       int           `json:"order_id"`
+	IssuedAt      time.Time     `json:"issued_at"`
+	OrderedAt     time.Time     `json:"ordered_at"`
+	Seller        InvoiceParty  `json:"seller"`
+	Customer      InvoiceParty  `json:"customer"`
+	Lines         []InvoiceLine `json:"lines"`
+	TaxBreakdown  []TaxSummary  `json:"tax_breakdown"`
+	Subtotal      float64       `json:"subtotal"`
+	DiscountTotal float64       `json:"discount_total"`
+	ShippingTotal float64       `json:"shipping_total"`
+	NetTotal      float64       `json:"net_total"`
+	TaxTotal      float64       `json:"tax_total"`
+	TotalPrice    float64       `json:"total_price"`
+}
+
+type InvoiceParty struct {
+	Name    string  `json:"name"`
+	Email   string  `json:"email,omitempty"`
+	Address Address `json:"address"`
+}
+
+type InvoiceLine struct {
+	Description    string  `json:"description"`
+	Quantity       int     `json:"quantity"`
+	UnitPrice      float64 `json:"unit_price"`
+	DiscountAmount float64 `json:"discount_amount"`
+	NetAmount      float64 `json:"net_amount"`
+	TaxRate        float64 `json:"tax_rate"`
+	TaxAmount      float64 `json:"tax_amount"`
+	GrossAmount    float64 `json:"gross_amount"`
+}
+
+// TaxSummary totals the lines of an order that are taxed at the same rate.
+type TaxSummary struct {
+	Rate      float64 `json:"rate"`
+	NetAmount float64 `json:"net_amount"`
+	TaxAmount float64 `json:"tax_amount"`
+}
+
+type PromotionSales struct {
+	PromotionID   int     `json:"promotion_id" db:"promotion_id"`
+	Name          string  `json:"name" db:"name"`
+	Code          string  `json:"code,omitempty" db:"code"`
+	Redemptions   int     `json:"redemptions" db:"redemptions"`
+	DiscountTotal float64 `json:"discount_total" db:"discount_total"`
+}
+
+type OrderStatusChange struct {
+	From      string    `json:"from" db:"from_status"`
+	To        string    `json:"to" db:"to_status"`
+	Note      string    `json:"note,omitempty" db:"note"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
+type ReturnRequest struct {
+	ID           int        `json:"id" db:"id"`
+	OrderID      int        `json:"order_id" db:"order_id"`
+	OrderItemID  int        `json:"order_item_id" db:"order_item_id"`
+	Quantity     int        `json:"quantity" db:"quantity"`
+	Reason       string     `json:"reason" db:"reason"`
+	Status       string     `json:"status" db:"status"`
+	DecisionNote string     `json:"decision_note,omitempty" db:"decision_note"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	Refund       *Refund    `json:"refund,omitempty" db:"-"`
+}
+
+type Refund struct {
+	ID              int       `json:"id" db:"id"`
+	OrderID         int       `json:"order_id" db:"order_id"`
+	ReturnRequestID int       `json:"return_request_id" db:"return_request_id"`
+	Book            Book      `json:"book" db:"book"`
+	Quantity        int       `json:"quantity" db:"quantity"`
+	Amount          float64   `json:"amount" db:"amount"`
+	TaxAmount       float64   `json:"tax_amount" db:"tax_amount"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+type CheckoutItem struct {
+	BookID   int `json:"book_id" db:"book_id"`
+	Quantity int `json:"quantity" db:"quantity"`
+}
+
+// CheckoutRequest describes an order to place. When Items is empty the cart of the session is checked out.
+type CheckoutRequest struct {
+	CustomerID     int            `json:"customer_id"`
+	Items          []CheckoutItem `json:"items"`
+	PromotionCodes []string       `json:"promotion_codes"`
+	SessionKey     string         `json:"-"`
+}
+
+// User is an account that can log in. User.CustomerID links it to the customer it buys as, when any.
+type User struct {
+	ID                int            `json:"id" db:"id"`
+	Email             string         `json:"email" db:"email"`
+	Name              string         `json:"name" db:"name"`
+	PasswordHash      string         `json:"-" db:"password_hash"`
+	CustomerID        int            `json:"customer_id,omitempty" db:"customer_id"`
+	Roles             pq.StringArray `json:"roles" db:"roles"`
+	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
+	PasswordChangedAt time.Time      `json:"password_changed_at" db:"password_changed_at"`
+}
+
+// SigningKey is an RSA key signing access tokens, PrivateKey is PKCS #8 PEM.
+type SigningKey struct {
+	Kid        string     `db:"kid"`
+	PrivateKey string     `db:"private_key"`
+	CreatedAt  time.Time  `db:"created_at"`
+	RetiredAt  *time.Time `db:"retired_at"`
+}
+
+// Session is a login of a user. ExpiresAt is the absolute timeout, IdleExpiresAt is computed from LastSeenAt.
+type Session struct {
+	ID            string     `json:"id" db:"id"`
+	UserID        int        `json:"user_id" db:"user_id"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	IdleExpiresAt time.Time  `json:"idle_expires_at" db:"-"`
+	ClientIP      string     `json:"client_ip" db:"client_ip"`
+	UserAgent     string     `json:"user_agent" db:"user_agent"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	Current       bool       `json:"current" db:"-"`
+}
+
+// APIKey is a long-lived credential of an integration. Its secret is only returned when it is created or rotated.
+type APIKey struct {
+	ID                      int            `json:"id" db:"id"`
+	Name                    string         `json:"name" db:"name"`
+	Prefix                  string         `json:"prefix" db:"prefix"`
+	SecretHash              string         `json:"-" db:"secret_hash"`
+	PreviousSecretHash      string         `json:"-" db:"previous_secret_hash"`
+	PreviousSecretExpiresAt *time.Time     `json:"previous_secret_expires_at,omitempty" db:"previous_secret_expires_at"`
+	Roles                   pq.StringArray `json:"roles" db:"roles"`
+	Scopes                  pq.StringArray `json:"scopes" db:"scopes"`
+	AllowedIPs              pq.StringArray `json:"allowed_ips" db:"allowed_ips"`
+	CreatedBy               int            `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt               time.Time      `json:"created_at" db:"created_at"`
+	ExpiresAt               *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
+	RotatedAt               *time.Time     `json:"rotated_at,omitempty" db:"rotated_at"`
+	LastUsedAt              *time.Time     `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP              string         `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	RevokedAt               *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// RefreshToken is a stored refresh token, the token itself is only known by its hash.
+type RefreshToken struct {
+	FamilyID  string     `db:"family_id"`
+	UserID    int        `db:"user_id"`
+	Scope     string     `db:"scope"`
+	IssuedAt  time.Time  `db:"issued_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
 }
 
 type Customer struct {
@@ -59,11 +375,24 @@ type BookSales struct {
 	Quantity int  `json:"quantity_sold" db:"quantity_sold"`
 }
 
+type PublisherSales struct {
+	Publisher Publisher `json:"publisher" db:"publisher"`
+	Quantity  int       `json:"quantity_sold" db:"quantity_sold"`
+	Revenue   float64   `json:"revenue" db:"revenue"`
+}
+
+// SalesReport.TotalRevenue is the gross revenue, taxes included, it splits into NetRevenue and TotalTax.
 type SalesReport struct {
-	Timestamp       time.Time   `json:"timestamp" db:"timestamp"`
-	TotalRevenue    float64     `json:"total_revenue" db:"total_revenue"`
-	TotalOrders     int         `json:"total_orders" db:"total_orders"`
-	TopSellingBooks []BookSales `json:"top_selling_books" db:"top_selling_books"`
+	Timestamp        time.Time        `json:"timestamp" db:"timestamp"`
+	TotalRevenue     float64          `json:"total_revenue" db:"total_revenue"`
+	NetRevenue       float64          `json:"net_revenue" db:"net_revenue"`
+	TotalTax         float64          `json:"total_tax" db:"total_tax"`
+	TotalRefunds     float64          `json:"total_refunds" db:"total_refunds"`
+	TotalDiscounts   float64          `json:"total_discounts" db:"total_discounts"`
+	TotalOrders      int              `json:"total_orders" db:"total_orders"`
+	TopSellingBooks  []BookSales      `json:"top_selling_books" db:"top_selling_books"`
+	SalesByPublisher []PublisherSales `json:"sales_by_publisher" db:"sales_by_publisher"`
+	Promotions       []PromotionSales `json:"promotions" db:"promotions"`
 }
 
 type ErrorResponse struct {
@@ -71,7 +400,9 @@ type ErrorResponse struct {
 }
 
 type SearchCriteria struct {
-	Title  string `json:"title"`
+	Title      string `json:"title"`
 	AuthorName string `json:"author_name"`
-	Genre  string `json:"genre"`
-}
\ No newline at end of file
+	Genre      string `json:"genre"`
+	Publisher  string `json:"publisher"`
+	SortBy     string `json:"sort"`
+}
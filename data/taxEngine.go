@@ -0,0 +1,83 @@
+package data
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultTaxCategory is the category of books created without one.
+const DefaultTaxCategory = "books"
+
+// loadTaxRules returns the rules of a country, countries are compared case-insensitively.
+func loadTaxRules(template *DBTemplate, country string) ([]TaxRule, error) {
+	return QueryStructs[TaxRule](template, taxRuleSelectQuery+`
+		WHERE LOWER(country) = LOWER($1)`, strings.TrimSpace(country))
+}
+
+// taxRuleFor picks the rule to apply to a category for an address. A rule for the state beats a
+// country-wide one, and a rule for the category beats the default rate of the country.
+func taxRuleFor(rules []TaxRule, address Address, category string) (TaxRule, bool) {
+	best, bestScore := TaxRule{}, -1
+	for _, rule := range rules {
+		if !strings.EqualFold(rule.Country, strings.TrimSpace(address.Country)) {
+			continue
+		}
+		score := 0
+		if rule.State != "" {
+			if !strings.EqualFold(rule.State, strings.TrimSpace(address.State)) {
+				continue
+			}
+			score += 2
+		}
+		if rule.Category != "" {
+			if !strings.EqualFold(rule.Category, category) {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rule, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// applyTaxes sets the tax rate and amount of each order item from the rules matching the customer address.
+// Tax is computed on the discounted line amount and rounded per line. Lines without a rule are not taxed.
+func applyTaxes(order *Order, rules []TaxRule) {
+	for i, item := range order.Items {
+		category := item.Book.TaxCategory
+		if category == "" {
+			category = DefaultTaxCategory
+		}
+		rule, found := taxRuleFor(rules, order.Customer.Address, category)
+		if !found {
+			order.Items[i].TaxRate, order.Items[i].TaxAmount = 0, 0
+			continue
+		}
+		net := roundMoney(item.UnitPrice*float64(item.Quantity) - item.DiscountAmount)
+		order.Items[i].TaxRate = rule.Rate
+		order.Items[i].TaxAmount = roundMoney(net * rule.Rate / 100)
+	}
+}
+
+// taxBreakdown groups the order items by tax rate, as shown on invoices.
+func taxBreakdown(items []OrderItem) []TaxSummary {
+	byRate := make(map[float64]*TaxSummary)
+	for _, item := range items {
+		if _, exists := byRate[item.TaxRate]; !exists {
+			byRate[item.TaxRate] = &TaxSummary{Rate: item.TaxRate}
+		}
+		byRate[item.TaxRate].NetAmount += item.LineTotal
+		byRate[item.TaxRate].TaxAmount += item.TaxAmount
+	}
+
+	summaries := make([]TaxSummary, 0, len(byRate))
+	for _, summary := range byRate {
+		summary.NetAmount = roundMoney(summary.NetAmount)
+		summary.TaxAmount = roundMoney(summary.TaxAmount)
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Rate < summaries[j].Rate })
+	return summaries
+}
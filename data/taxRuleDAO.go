@@ -0,0 +1,86 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrTaxRuleNotFound = errors.New("tax rule not found")
+	ErrTaxRuleExists   = errors.New("a tax rule already exists for this country, state and category")
+)
+
+const taxRuleSelectQuery = `
+		SELECT id, name, country, state, category, rate
+		FROM tax_rules`
+
+type TaxRuleRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewTaxRuleRepository(dbTemplate *DBTemplate) *TaxRuleRepository {
+	return &TaxRuleRepository{
+		dbTemplate: dbTemplate.Repository("tax_rule"),
+	}
+}
+
+func (repo *TaxRuleRepository) Create(rule TaxRule) (TaxRule, error) {
+	query := `
+		INSERT INTO tax_rules (name, country, state, category, rate)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, rule.Name, rule.Country, rule.State, rule.Category, rule.Rate)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return TaxRule{}, ErrTaxRuleExists
+		}
+		return TaxRule{}, err
+	}
+	rule.ID = id
+	return rule, nil
+}
+
+func (repo *TaxRuleRepository) GetById(id int) (TaxRule, error) {
+	rule, err := QueryStruct[TaxRule](repo.dbTemplate, taxRuleSelectQuery+`
+		WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TaxRule{}, ErrTaxRuleNotFound
+		}
+		return TaxRule{}, err
+	}
+	return *rule, nil
+}
+
+func (repo *TaxRuleRepository) Update(id int, updated TaxRule) (TaxRule, error) {
+	query := `
+		UPDATE tax_rules SET name = $1, country = $2, state = $3, category = $4, rate = $5
+		WHERE id = $6`
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, query, updated.Name, updated.Country, updated.State, updated.Category, updated.Rate, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return TaxRule{}, ErrTaxRuleExists
+		}
+		return TaxRule{}, err
+	}
+	if rowsAffected == 0 {
+		return TaxRule{}, ErrTaxRuleNotFound
+	}
+	updated.ID = id
+	return updated, nil
+}
+
+func (repo *TaxRuleRepository) Delete(id int) error {
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, `DELETE FROM tax_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTaxRuleNotFound
+	}
+	return nil
+}
+
+func (repo *TaxRuleRepository) GetAll() ([]TaxRule, error) {
+	return QueryStructs[TaxRule](repo.dbTemplate, taxRuleSelectQuery+`
+		ORDER BY country, state, category`)
+}
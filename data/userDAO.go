@@ -0,0 +1,266 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// RoleCustomer is the role given to registered users.
+	RoleCustomer = "customer"
+
+	MinPasswordLength = 8
+	// MaxPasswordLength is the bcrypt limit, longer passwords would be silently truncated.
+	MaxPasswordLength = 72
+
+	PasswordResetValidity = time.Hour
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrEmailTaken           = errors.New("an account already exists for this email")
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrPasswordTooShort     = errors.New("password must be at least 8 characters long")
+	ErrPasswordTooLong      = errors.New("password must be at most 72 bytes long")
+	ErrInvalidResetToken    = errors.New("password reset token is invalid or expired")
+	ErrInvalidEmail         = errors.New("email is not valid")
+	ErrCustomerAlreadyTaken = errors.New("customer is already linked to another account")
+)
+
+// dummyPasswordHash is compared against when the email is unknown, so a failed login takes
+// as long whether or not the account exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+const userSelectQuery = `
+		SELECT id, email, name, password_hash, COALESCE(customer_id, 0) AS customer_id, roles, created_at, password_changed_at
+		FROM users`
+
+type UserRepository struct {
+	dbTemplate *DBTemplate
+}
+
+func NewUserRepository(dbTemplate *DBTemplate) *UserRepository {
+	return &UserRepository{
+		dbTemplate: dbTemplate.Repository("user"),
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func hashPassword(password string) (string, error) {
+	switch {
+	case len(password) < MinPasswordLength:
+		return "", ErrPasswordTooShort
+	case len(password) > MaxPasswordLength:
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Register creates an account, emails are case-insensitive. The account is linked to no customer,
+// see SetCustomer.
+func (repo *UserRepository) Register(user User, password string) (User, error) {
+	user.Email = normalizeEmail(user.Email)
+	if at := strings.Index(user.Email, "@"); at < 1 || at == len(user.Email)-1 {
+		return User{}, ErrInvalidEmail
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	user.PasswordHash = hash
+	user.Roles = pq.StringArray{RoleCustomer}
+	user.CreatedAt = time.Now().UTC()
+	user.PasswordChangedAt = user.CreatedAt
+	user.CustomerID = 0
+	query := `
+		INSERT INTO users (email, name, password_hash, roles, created_at, password_changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	id, err := ExecuteInsert(repo.dbTemplate, query, user.Email, user.Name, user.PasswordHash, user.Roles,
+		user.CreatedAt, user.PasswordChangedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return User{}, ErrEmailTaken
+		}
+		return User{}, err
+	}
+	user.ID = id
+	return user, nil
+}
+
+func (repo *UserRepository) GetById(id int) (User, error) {
+	user, err := QueryStruct[User](repo.dbTemplate, userSelectQuery+`
+		WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrUserNotFound
+		}
+		return User{}, err
+	}
+	return *user, nil
+}
+
+// SetRoles replaces the roles of a user.
+func (repo *UserRepository) SetRoles(id int, roles []string) (User, error) {
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, `
+		UPDATE users SET roles = $1
+		WHERE id = $2`, pq.StringArray(roles), id)
+	if err != nil {
+		return User{}, err
+	}
+	if rowsAffected == 0 {
+		return User{}, ErrUserNotFound
+	}
+	return repo.GetById(id)
+}
+
+// SetCustomer links a user to the customer it buys as, 0 unlinks it. A customer belongs to one user at most.
+func (repo *UserRepository) SetCustomer(id int, customerID int) (User, error) {
+	rowsAffected, err := ExecuteUpdateOrDelete(repo.dbTemplate, `
+		UPDATE users SET customer_id = NULLIF($1, 0)
+		WHERE id = $2`, customerID, id)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return User{}, ErrCustomerAlreadyTaken
+		case isForeignKeyViolation(err, "users_customer_id_fkey"):
+			return User{}, ErrCustomerNotFound
+		}
+		return User{}, err
+	}
+	if rowsAffected == 0 {
+		return User{}, ErrUserNotFound
+	}
+	return repo.GetById(id)
+}
+
+func (repo *UserRepository) getByEmail(email string) (User, error) {
+	user, err := QueryStruct[User](repo.dbTemplate, userSelectQuery+`
+		WHERE email = $1`, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrUserNotFound
+		}
+		return User{}, err
+	}
+	return *user, nil
+}
+
+// Authenticate checks an email and password pair and returns the matching account.
+func (repo *UserRepository) Authenticate(email string, password string) (User, error) {
+	user, err := repo.getByEmail(email)
+	if errors.Is(err, ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func (repo *UserRepository) setPassword(template *DBTemplate, userID int, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := ExecuteUpdateOrDelete(template, `
+		UPDATE users SET password_hash = $1, password_changed_at = $2
+		WHERE id = $3`, hash, time.Now().UTC(), userID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of an account, the current password must be given.
+func (repo *UserRepository) ChangePassword(userID int, currentPassword string, newPassword string) error {
+	user, err := repo.GetById(userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)) != nil {
+		return ErrInvalidCredentials
+	}
+	return repo.setPassword(repo.dbTemplate, userID, newPassword)
+}
+
+// CreatePasswordReset issues a single-use reset token for the account of an email.
+// ErrUserNotFound is returned for unknown emails, callers should not reveal it.
+func (repo *UserRepository) CreatePasswordReset(email string) (User, string, error) {
+	user, err := repo.getByEmail(email)
+	if err != nil {
+		return User{}, "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return User{}, "", err
+	}
+	token := hex.EncodeToString(secret)
+	now := time.Now().UTC()
+	_, err = ExecuteUpdateOrDelete(repo.dbTemplate, `
+		INSERT INTO password_resets (token_hash, user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)`, hashToken(token), user.ID, now, now.Add(PasswordResetValidity))
+	if err != nil {
+		return User{}, "", err
+	}
+	return user, token, nil
+}
+
+// ResetPassword sets a new password with a reset token. The token is used up, and so are the
+// other pending tokens of the account.
+func (repo *UserRepository) ResetPassword(token string, newPassword string) (User, error) {
+	var userID int
+	err := InTransaction(repo.dbTemplate, func(tx *DBTemplate) error {
+		id, err := QueryStruct[int](tx, `
+			SELECT user_id FROM password_resets
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+			FOR UPDATE`, hashToken(token), time.Now().UTC())
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		userID = *id
+
+		if err := repo.setPassword(tx, userID, newPassword); err != nil {
+			return err
+		}
+		_, err = ExecuteUpdateOrDelete(tx, `
+			UPDATE password_resets SET used_at = $1
+			WHERE user_id = $2 AND used_at IS NULL`, time.Now().UTC(), userID)
+		return err
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return repo.GetById(userID)
+}
@@ -0,0 +1,58 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail scales src down so that it fits within maxWidth x maxHeight while keeping
+// its aspect ratio. Images that already fit are returned unchanged. Every destination
+// pixel is the average of the source pixels it covers (box filter), which avoids the
+// aliasing of nearest-neighbour sampling when shrinking large covers.
+func Thumbnail(src image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return src
+	}
+
+	dstWidth, dstHeight := maxWidth, height*maxWidth/width
+	if dstHeight > maxHeight {
+		dstWidth, dstHeight = width*maxHeight/height, maxHeight
+	}
+	dstWidth = max(dstWidth, 1)
+	dstHeight = max(dstHeight, 1)
+
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		sy0 := y * height / dstHeight
+		sy1 := max((y+1)*height/dstHeight, sy0+1)
+		for x := 0; x < dstWidth; x++ {
+			sx0 := x * width / dstWidth
+			sx1 := max((x+1)*width/dstWidth, sx0+1)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					px := row[sx*4 : sx*4+4]
+					r += uint64(px[0])
+					g += uint64(px[1])
+					b += uint64(px[2])
+					a += uint64(px[3])
+					n++
+				}
+			}
+
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
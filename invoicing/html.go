@@ -0,0 +1,36 @@
+package invoicing
+
+import (
+	"embed"
+	"finalproject/data"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+)
+
+//go:embed templates/invoice.html
+var templates embed.FS
+
+var invoiceTemplate = template.Must(template.New("invoice.html").Funcs(template.FuncMap{
+	"money": formatMoney,
+	"rate":  formatRate,
+	"date":  formatDate,
+}).ParseFS(templates, "templates/invoice.html"))
+
+func formatMoney(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// HTML renders an invoice as a standalone HTML page.
+func HTML(w io.Writer, invoice data.Invoice) error {
+	return invoiceTemplate.Execute(w, invoice)
+}
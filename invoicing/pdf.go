@@ -0,0 +1,275 @@
+package invoicing
+
+import (
+	"bytes"
+	"finalproject/data"
+	"fmt"
+	"strings"
+)
+
+// A4 in points, the unit of PDF coordinates. The origin is the bottom left corner of the page.
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 50.0
+)
+
+const (
+	regular = "F1"
+	bold    = "F2"
+)
+
+// helveticaWidths are the advance widths of the printable ASCII characters in Helvetica, in
+// thousandths of the font size, as published in the Adobe font metrics. They are only needed to
+// right-align amounts and to truncate long descriptions, so the bold variant reuses them.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+func textWidth(text string, size float64) float64 {
+	width := 0
+	for _, r := range text {
+		if r >= 32 && r <= 126 {
+			width += helveticaWidths[r-32]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// truncate shortens text with an ellipsis so that it fits in the given width.
+func truncate(text string, size float64, width float64) string {
+	if textWidth(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// winAnsiReplacements maps the characters of Windows-1252 that differ from Latin-1.
+var winAnsiReplacements = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '–': 0x96, '—': 0x97,
+}
+
+// pdfString encodes text as a PDF literal string in WinAnsiEncoding, the encoding of the standard
+// fonts. Characters the encoding does not have are replaced by a question mark.
+func pdfString(text string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			if c, ok := winAnsiReplacements[r]; ok {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+func (p *pdfPage) text(x, y float64, font string, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td %s Tj ET\n", font, size, x, y, pdfString(text))
+}
+
+func (p *pdfPage) textRight(right, y float64, font string, size float64, text string) {
+	p.text(right-textWidth(text, size), y, font, size, text)
+}
+
+func (p *pdfPage) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// pdfDocument lays out pages and writes them as a PDF 1.4 file using the Helvetica standard fonts,
+// which every PDF reader provides, so no font has to be embedded.
+type pdfDocument struct {
+	pages []*pdfPage
+}
+
+func (d *pdfDocument) newPage() *pdfPage {
+	page := &pdfPage{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+func (d *pdfDocument) bytes(footer func(page, pages int) string) []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 to 4 are the catalog, the page tree and the two fonts, each page then takes two objects.
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		if footer != nil {
+			page.textRight(pageWidth-margin, 30, regular, 8, footer(i+1, len(d.pages)))
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, regular, bold, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// lineColumns are the right edges of the amount columns of the line table, the description takes the rest.
+var lineColumns = []struct {
+	title string
+	right float64
+}{
+	{"Qty", 270}, {"Unit price", 320}, {"Discount", 370}, {"Net", 420}, {"Tax rate", 460}, {"Tax", 500}, {"Total", pageWidth - margin},
+}
+
+func partyLines(party data.InvoiceParty) []string {
+	lines := []string{party.Name}
+	address := party.Address
+	if address.Street != "" {
+		lines = append(lines, address.Street)
+	}
+	if city := strings.TrimSpace(address.PostalCode + " " + address.City); city != "" {
+		lines = append(lines, city)
+	}
+	if address.State != "" {
+		lines = append(lines, address.State)
+	}
+	if address.Country != "" {
+		lines = append(lines, address.Country)
+	}
+	if party.Email != "" {
+		lines = append(lines, party.Email)
+	}
+	return lines
+}
+
+func tableHeader(page *pdfPage, y float64) {
+	page.text(margin, y, bold, 9, "Description")
+	for _, column := range lineColumns {
+		page.textRight(column.right, y, bold, 9, column.title)
+	}
+	page.line(margin, y-5, pageWidth-margin, y-5, 1)
+}
+
+// PDF renders an invoice as a PDF document. Long invoices continue on further pages.
+func PDF(invoice data.Invoice) []byte {
+	const rowHeight = 15.0
+	doc := &pdfDocument{}
+	page := doc.newPage()
+
+	page.text(margin, 780, bold, 22, "Invoice")
+	page.text(margin, 760, regular, 11, invoice.Number)
+	page.textRight(pageWidth-margin, 780, regular, 10, "Issued "+formatDate(invoice.IssuedAt))
+	page.textRight(pageWidth-margin, 765, regular, 10, fmt.Sprintf("Order #%d of %s", invoice.OrderID, formatDate(invoice.OrderedAt)))
+
+	page.text(margin, 720, bold, 8, "FROM")
+	page.text(320, 720, bold, 8, "BILL TO")
+	for i, text := range partyLines(invoice.Seller) {
+		page.text(margin, 705-float64(i)*13, regular, 10, text)
+	}
+	for i, text := range partyLines(invoice.Customer) {
+		page.text(320, 705-float64(i)*13, regular, 10, text)
+	}
+
+	y := 600.0
+	tableHeader(page, y)
+	y -= rowHeight + 5
+	descriptionWidth := lineColumns[0].right - 30 - margin
+	for _, line := range invoice.Lines {
+		if y < 80 {
+			page = doc.newPage()
+			y = pageHeight - margin - 20
+			tableHeader(page, y)
+			y -= rowHeight + 5
+		}
+		page.text(margin, y, regular, 9, truncate(line.Description, 9, descriptionWidth))
+		values := []string{
+			fmt.Sprint(line.Quantity), formatMoney(line.UnitPrice), formatMoney(line.DiscountAmount), formatMoney(line.NetAmount),
+			formatRate(line.TaxRate), formatMoney(line.TaxAmount), formatMoney(line.GrossAmount),
+		}
+		for i, value := range values {
+			page.textRight(lineColumns[i].right, y, regular, 9, value)
+		}
+		y -= rowHeight
+	}
+
+	totals := [][2]string{
+		{"Subtotal", formatMoney(invoice.Subtotal)},
+		{"Discounts", "-" + formatMoney(invoice.DiscountTotal)},
+		{"Shipping", formatMoney(invoice.ShippingTotal)},
+		{"Net total", formatMoney(invoice.NetTotal)},
+		{"Tax", formatMoney(invoice.TaxTotal)},
+	}
+	// The totals and the tax summary are kept together on the last page.
+	needed := float64(len(totals)+3+len(invoice.TaxBreakdown)) * rowHeight
+	if y-needed < 60 {
+		page = doc.newPage()
+		y = pageHeight - margin - 20
+	}
+	y -= 10
+	page.line(350, y+rowHeight-3, pageWidth-margin, y+rowHeight-3, 0.5)
+	for _, total := range totals {
+		page.text(350, y, regular, 10, total[0])
+		page.textRight(pageWidth-margin, y, regular, 10, total[1])
+		y -= rowHeight
+	}
+	page.line(350, y+rowHeight-3, pageWidth-margin, y+rowHeight-3, 1)
+	page.text(350, y, bold, 11, "Total")
+	page.textRight(pageWidth-margin, y, bold, 11, formatMoney(invoice.TotalPrice))
+
+	if len(invoice.TaxBreakdown) > 0 {
+		y -= 2 * rowHeight
+		page.text(margin, y, bold, 9, "Tax rate")
+		page.textRight(200, y, bold, 9, "Net")
+		page.textRight(260, y, bold, 9, "Tax")
+		for _, summary := range invoice.TaxBreakdown {
+			y -= rowHeight
+			page.text(margin, y, regular, 9, formatRate(summary.Rate))
+			page.textRight(200, y, regular, 9, formatMoney(summary.NetAmount))
+			page.textRight(260, y, regular, 9, formatMoney(summary.TaxAmount))
+		}
+	}
+
+	return doc.bytes(func(page, pages int) string {
+		return fmt.Sprintf("%s - page %d of %d", invoice.Number, page, pages)
+	})
+}
@@ -0,0 +1,33 @@
+// Package logging carries the request-scoped structured logger through contexts, so handlers
+// and repositories log with the request ID of the request they serve.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// WithLogger returns a context carrying a logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, "logger", logger)
+}
+
+// FromContext returns the logger of a context, or the default logger when it carries none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value("logger").(*slog.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// ParseLevel reads a level name (debug, info, warn, error), info when it is empty or unknown.
+func ParseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
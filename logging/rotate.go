@@ -0,0 +1,292 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names the rotated segments, it sorts the same way as the times it formats.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotationPolicy tells when a RotatingFile starts a new segment and how many old ones it keeps.
+type RotationPolicy struct {
+	MaxSize    int64         // bytes per segment, no limit if zero
+	Interval   time.Duration // segments are cut at each multiple of Interval (UTC), never if zero
+	MaxBackups int           // rotated segments kept, all of them if zero
+	Compress   bool          // gzip the rotated segments
+}
+
+// DefaultRotationPolicy rotates daily or at 100 MB, and keeps a week of compressed segments.
+var DefaultRotationPolicy = RotationPolicy{
+	MaxSize:    100 << 20,
+	Interval:   24 * time.Hour,
+	MaxBackups: 7,
+	Compress:   true,
+}
+
+// RotatingFile is an io.Writer appending to a file, renamed to a timestamped segment
+// (requests-2026-10-19T00-00-00.000.log) when it grows past the policy size or interval.
+type RotatingFile struct {
+	path   string
+	policy RotationPolicy
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// mill serializes the compression and pruning of the rotated segments.
+	mill sync.Mutex
+}
+
+// NewRotatingFile opens path for appending, creating it if needed.
+func NewRotatingFile(path string, policy RotationPolicy) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, policy: policy}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the file at the path. An existing file counts as opened when it was last
+// written, so a segment left over from a previous interval is rotated on the first write.
+func (r *RotatingFile) open() error {
+	if dir := filepath.Dir(r.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = time.Now()
+	if info.Size() > 0 {
+		r.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.due(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// due reports whether writing n more bytes must go to a new segment.
+func (r *RotatingFile) due(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.policy.MaxSize > 0 && r.size+n > r.policy.MaxSize {
+		return true
+	}
+	if r.policy.Interval > 0 {
+		return !time.Now().UTC().Truncate(r.policy.Interval).Equal(r.openedAt.UTC().Truncate(r.policy.Interval))
+	}
+	return false
+}
+
+// rotate renames the current file to a segment and opens a new one.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	backup := r.backupName(time.Now().UTC())
+	if err := os.Rename(r.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	go r.millBackups(backup)
+	return nil
+}
+
+// Rotate starts a new segment now.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return r.open()
+	}
+	return r.rotate()
+}
+
+// Reopen closes the file and opens the path again. Send SIGHUP after an external tool
+// such as logrotate has moved the file away, so the writes go to a new one.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// backupName is the path of the segment rotated at t.
+func (r *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(r.path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), t.Format(backupTimeFormat), ext)
+}
+
+// backups lists the rotated segments of the file, newest first.
+func (r *RotatingFile) backups() ([]string, error) {
+	ext := filepath.Ext(r.path)
+	prefix := filepath.Base(strings.TrimSuffix(r.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(r.path), name))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// millBackups compresses the segment just rotated and removes the ones past MaxBackups.
+func (r *RotatingFile) millBackups(backup string) {
+	r.mill.Lock()
+	defer r.mill.Unlock()
+
+	if r.policy.Compress {
+		if err := compressFile(backup); err != nil {
+			slog.Error("failed to compress log segment", "path", backup, "error", err)
+		}
+	}
+	if r.policy.MaxBackups <= 0 {
+		return
+	}
+	backups, err := r.backups()
+	if err != nil {
+		slog.Error("failed to list log segments", "path", r.path, "error", err)
+		return
+	}
+	for _, old := range backups[min(len(backups), r.policy.MaxBackups):] {
+		if err := os.Remove(old); err != nil {
+			slog.Error("failed to remove log segment", "path", old, "error", err)
+		}
+	}
+}
+
+// compressFile replaces path by path.gz.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// Log destinations.
+const (
+	OutputFile   = "file"
+	OutputStdout = "stdout"
+	OutputBoth   = "both"
+)
+
+// OpenOutput returns the writer of a log destination: the standard output, a rotating file
+// at path, or both. The rotating file is returned too, nil for stdout, so it can be reopened.
+func OpenOutput(output, path string, policy RotationPolicy) (io.Writer, *RotatingFile, error) {
+	switch strings.ToLower(strings.TrimSpace(output)) {
+	case OutputStdout:
+		return os.Stdout, nil, nil
+	case "", OutputFile:
+		file, err := NewRotatingFile(path, policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, file, nil
+	case OutputBoth:
+		file, err := NewRotatingFile(path, policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		return io.MultiWriter(os.Stdout, file), file, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown log output %q, expected file, stdout or both", output)
+	}
+}
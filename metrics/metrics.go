@@ -0,0 +1,379 @@
+// Package metrics keeps counters, gauges and histograms and serves them in the Prometheus
+// text exposition format (version 0.0.4).
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector writes the samples of one metric family.
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+// register adds a metric to the exposition. Names are unique, registering one twice is a bug.
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range registry {
+		if existing.name() == c.name() {
+			panic("metrics: duplicate metric " + c.name())
+		}
+	}
+	registry = append(registry, c)
+}
+
+// Handler serves every registered metric.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// Write writes every registered metric, sorted by name.
+func Write(w io.Writer) {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+
+	buf := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	buf.Flush()
+}
+
+// family holds what every metric type shares: name, help and label names.
+type family struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (f family) name() string {
+	return f.metricName
+}
+
+func (f family) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.metricName, escapeHelp(f.help), f.metricName, kind)
+}
+
+// labelPairs formats label names and values as {a="x",b="y"}, extra pairs appended.
+func labelPairs(names []string, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, escapeLabel(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], escapeLabel(extra[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// atomicFloat is a float64 updated without locks.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) set(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// series keeps the children of a labelled metric by their label values.
+type series[T any] struct {
+	mu       sync.Mutex
+	children map[string]*T
+	values   map[string][]string
+	create   func() *T
+}
+
+func newSeries[T any](create func() *T) *series[T] {
+	return &series[T]{children: map[string]*T{}, values: map[string][]string{}, create: create}
+}
+
+func (s *series[T]) get(labels []string, values []string) *T {
+	if len(values) != len(labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	child, ok := s.children[key]
+	if !ok {
+		child = s.create()
+		s.children[key] = child
+		s.values[key] = append([]string(nil), values...)
+	}
+	return child
+}
+
+// each calls fn for every child, sorted by label values.
+func (s *series[T]) each(fn func(values []string, child *T)) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.children))
+	for key := range s.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]*T, len(keys))
+	values := make([][]string, len(keys))
+	for i, key := range keys {
+		children[i], values[i] = s.children[key], s.values[key]
+	}
+	s.mu.Unlock()
+
+	for i := range keys {
+		fn(values[i], children[i])
+	}
+}
+
+// Counter only goes up.
+type Counter struct {
+	value atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.value.add(1)
+}
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.value.add(v)
+}
+
+// CounterVec is a counter per set of label values.
+type CounterVec struct {
+	family
+	series *series[Counter]
+}
+
+// NewCounterVec registers a counter with the given labels.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{family{name, help, labels}, newSeries(func() *Counter { return &Counter{} })}
+	register(c)
+	return c
+}
+
+// With returns the counter of the label values, given in the order of the labels.
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.series.get(c.labels, values)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.header(w, "counter")
+	c.series.each(func(values []string, child *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, labelPairs(c.labels, values), formatFloat(child.value.load()))
+	})
+}
+
+// Gauge goes up and down.
+type Gauge struct {
+	family
+	value atomicFloat
+}
+
+// NewGauge registers a gauge without labels.
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{family: family{metricName: name, help: help}}
+	register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64) {
+	g.value.set(v)
+}
+
+func (g *Gauge) Add(v float64) {
+	g.value.add(v)
+}
+
+func (g *Gauge) Inc() {
+	g.value.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.value.add(-1)
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.header(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(g.value.load()))
+}
+
+// funcMetric reads its samples when it is scraped, from values kept elsewhere.
+type funcMetric struct {
+	family
+	kind  string
+	label string
+	read  func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge read from fn on every scrape.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(&funcMetric{family{metricName: name, help: help}, "gauge", "", func() map[string]float64 {
+		return map[string]float64{"": fn()}
+	}})
+}
+
+// NewCounterFunc registers a counter read from fn on every scrape, fn must never decrease.
+func NewCounterFunc(name, help string, fn func() float64) {
+	register(&funcMetric{family{metricName: name, help: help}, "counter", "", func() map[string]float64 {
+		return map[string]float64{"": fn()}
+	}})
+}
+
+// NewGaugeVecFunc registers a gauge with one label, read from fn on every scrape as a map
+// from label value to value. fn may return nil when the values can't be read.
+func NewGaugeVecFunc(name, help, label string, fn func() map[string]float64) {
+	register(&funcMetric{family{name, help, []string{label}}, "gauge", label, fn})
+}
+
+func (m *funcMetric) write(w io.Writer) {
+	samples := m.read()
+	m.header(w, m.kind)
+	keys := make([]string, 0, len(samples))
+	for key := range samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		labels := ""
+		if m.label != "" {
+			labels = labelPairs([]string{m.label}, []string{key})
+		}
+		fmt.Fprintf(w, "%s%s %s\n", m.metricName, labels, formatFloat(samples[key]))
+	}
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// HistogramVec is a histogram per set of label values.
+type HistogramVec struct {
+	family
+	buckets []float64
+	series  *series[Histogram]
+}
+
+// NewHistogramVec registers a histogram with the given buckets, sorted upper bounds, and labels.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{family: family{name, help, labels}, buckets: buckets}
+	h.series = newSeries(func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})
+	register(h)
+	return h
+}
+
+// With returns the histogram of the label values, given in the order of the labels.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.series.get(h.labels, values)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.series.each(func(values []string, child *Histogram) {
+		child.mu.Lock()
+		counts := append([]uint64(nil), child.counts...)
+		count, sum := child.count, child.sum
+		child.mu.Unlock()
+
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labelPairs(h.labels, values, "le", formatFloat(bound)), counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labelPairs(h.labels, values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, labelPairs(h.labels, values), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, labelPairs(h.labels, values), count)
+	})
+}
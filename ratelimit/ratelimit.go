@@ -0,0 +1,121 @@
+// Package ratelimit implements token bucket rate limiting. Buckets are kept by a Store, in memory
+// for a single instance or in a shared store so limits hold across instances.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per period Per, in bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Rate is the number of tokens added to a bucket per second.
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until a token is available, zero when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take refills the bucket of a key for the time elapsed, then takes a token
+// from it when there is one.
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Refill returns the tokens of a bucket after some time, capped at the capacity of the limit.
+func Refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.Rate())
+}
+
+// NewResult describes a bucket holding tokens after a request was, or was not, allowed.
+func NewResult(allowed bool, tokens float64, limit Limit) Result {
+	rate := limit.Rate()
+	result := Result{
+		Allowed:    allowed,
+		Limit:      limit.Requests,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(limit.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     Limit
+}
+
+// MemoryStore keeps the buckets of a single instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(limit.Requests), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.tokens = Refill(b.tokens, now.Sub(b.updatedAt), limit)
+	b.updatedAt = now
+	b.limit = limit
+
+	if b.tokens < 1 {
+		return NewResult(false, b.tokens, limit), nil
+	}
+	b.tokens--
+	return NewResult(true, b.tokens, limit), nil
+}
+
+// Sweep forgets the buckets that have refilled, they are the same as new ones.
+func (s *MemoryStore) Sweep(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for key, b := range s.buckets {
+		if Refill(b.tokens, now.Sub(b.updatedAt), b.limit) >= float64(b.limit.Requests) {
+			delete(s.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartMemoryStoreJanitor periodically sweeps the refilled buckets of a store.
+func StartMemoryStoreJanitor(s *MemoryStore) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.Sweep(time.Now())
+	}
+}
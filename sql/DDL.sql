@@ -5,6 +5,13 @@ CREATE TABLE authors (
     bio TEXT
 );
 
+CREATE TABLE publishers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    country VARCHAR(100) NOT NULL DEFAULT '',
+    website VARCHAR(255) NOT NULL DEFAULT ''
+);
+
 CREATE TABLE books (
     id SERIAL PRIMARY KEY,
     title VARCHAR(255) NOT NULL,
@@ -12,8 +19,26 @@ CREATE TABLE books (
     published_at TIMESTAMP NOT NULL,
     price NUMERIC(10, 2) NOT NULL,
     stock INT NOT NULL,
+    rating_average NUMERIC(3, 2) NOT NULL DEFAULT 0,
+    rating_count INT NOT NULL DEFAULT 0,
+    tax_category VARCHAR(50) NOT NULL DEFAULT 'books',
+    weight_grams INT NOT NULL DEFAULT 0 CHECK (weight_grams >= 0),
 
-    author_id INT NOT NULL REFERENCES authors(id) ON DELETE CASCADE
+    author_id INT NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    publisher_id INT REFERENCES publishers(id) ON DELETE SET NULL
+);
+
+CREATE TABLE book_covers (
+    book_id INT PRIMARY KEY REFERENCES books(id) ON DELETE CASCADE,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    etag VARCHAR(100) NOT NULL,
+    thumbnail_content_type VARCHAR(100) NOT NULL,
+    thumbnail_size_bytes BIGINT NOT NULL,
+    thumbnail_etag VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
 
@@ -42,15 +67,311 @@ CREATE TABLE customers (
 CREATE TABLE orders (
     id SERIAL PRIMARY KEY,
     customer_id INT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    subtotal NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    discount_total NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    tax_total NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    shipping_total NUMERIC(10, 2) NOT NULL DEFAULT 0,
     total_price NUMERIC(10, 2) NOT NULL,
     created_at TIMESTAMP DEFAULT NOW(),
-    status VARCHAR(50) NOT NULL
+    status VARCHAR(50) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded'))
 );
 
+CREATE TABLE order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50) NOT NULL DEFAULT '',
+    to_status VARCHAR(50) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX order_status_history_order_id_idx ON order_status_history (order_id);
+
 
 CREATE TABLE order_items (
     id SERIAL PRIMARY KEY,
     order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    -- Books that were sold can't be deleted, their orders, returns and refunds are financial records.
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE RESTRICT,
+    quantity INT NOT NULL,
+    unit_price NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    line_total NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    tax_rate NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    tax_amount NUMERIC(10, 2) NOT NULL DEFAULT 0
+);
+
+
+CREATE TABLE reviews (
+    id SERIAL PRIMARY KEY,
     book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
-    quantity INT NOT NULL
+    customer_id INT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    UNIQUE (book_id, customer_id)
+);
+
+CREATE INDEX reviews_book_id_created_at_idx ON reviews (book_id, created_at DESC);
+
+
+CREATE TABLE carts (
+    id SERIAL PRIMARY KEY,
+    session_key VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX carts_updated_at_idx ON carts (updated_at);
+
+CREATE TABLE cart_items (
+    cart_id INT NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (cart_id, book_id)
 );
+
+
+CREATE TABLE return_requests (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_item_id INT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'approved', 'rejected')),
+    decision_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    decided_at TIMESTAMP
+);
+
+CREATE INDEX return_requests_order_id_idx ON return_requests (order_id);
+
+CREATE TABLE refunds (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    return_request_id INT UNIQUE REFERENCES return_requests(id) ON DELETE SET NULL,
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE RESTRICT,
+    quantity INT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    tax_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX refunds_created_at_idx ON refunds (created_at);
+
+
+CREATE TABLE promotions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(50) UNIQUE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('percent', 'fixed', 'buy_x_get_y')),
+    value NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    scope VARCHAR(20) NOT NULL DEFAULT 'order' CHECK (scope IN ('order', 'genre', 'author', 'book')),
+    scope_value VARCHAR(255) NOT NULL DEFAULT '',
+    min_spend NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    buy_quantity INT NOT NULL DEFAULT 0,
+    get_quantity INT NOT NULL DEFAULT 0,
+    usage_limit INT NOT NULL DEFAULT 0,
+    per_customer_limit INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE promotion_redemptions (
+    id SERIAL PRIMARY KEY,
+    promotion_id INT NOT NULL REFERENCES promotions(id) ON DELETE RESTRICT,
+    customer_id INT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL,
+    explanation TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX promotion_redemptions_promotion_id_idx ON promotion_redemptions (promotion_id, customer_id);
+CREATE INDEX promotion_redemptions_order_id_idx ON promotion_redemptions (order_id);
+
+
+CREATE TABLE tax_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    country VARCHAR(100) NOT NULL,
+    state VARCHAR(100) NOT NULL DEFAULT '',
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    rate NUMERIC(5, 2) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+
+    UNIQUE (country, state, category)
+);
+
+
+CREATE TABLE shipping_zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    countries TEXT[] NOT NULL,
+    rate_basis VARCHAR(20) NOT NULL CHECK (rate_basis IN ('weight', 'items')),
+    base_rate NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    unit_rate NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    free_shipping_threshold NUMERIC(10, 2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE shipments (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    carrier VARCHAR(100) NOT NULL,
+    tracking_number VARCHAR(100) NOT NULL,
+    status VARCHAR(30) NOT NULL DEFAULT 'label_created'
+        CHECK (status IN ('label_created', 'in_transit', 'out_for_delivery', 'delivered', 'exception')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX shipments_order_id_idx ON shipments (order_id);
+
+CREATE TABLE shipment_items (
+    shipment_id INT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id INT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+
+    PRIMARY KEY (shipment_id, order_item_id)
+);
+
+CREATE TABLE shipment_events (
+    id SERIAL PRIMARY KEY,
+    shipment_id INT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    status VARCHAR(30) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX shipment_events_shipment_id_idx ON shipment_events (shipment_id);
+
+
+-- Invoice numbers are taken from a counter row per year, locked for the length of the transaction,
+-- so numbering has no gaps. Issued invoices can be neither changed nor deleted.
+CREATE TABLE invoice_counters (
+    year INT PRIMARY KEY,
+    last_number INT NOT NULL
+);
+
+CREATE TABLE invoices (
+    id SERIAL PRIMARY KEY,
+    number VARCHAR(30) UNIQUE NOT NULL,
+    order_id INT UNIQUE NOT NULL REFERENCES orders(id) ON DELETE RESTRICT,
+    issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    snapshot JSONB NOT NULL
+);
+
+CREATE FUNCTION forbid_invoice_changes() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'issued invoices cannot be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER invoices_immutable
+    BEFORE UPDATE OR DELETE ON invoices
+    FOR EACH ROW EXECUTE FUNCTION forbid_invoice_changes();
+
+
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(150) UNIQUE NOT NULL,
+    name VARCHAR(150) NOT NULL DEFAULT '',
+    password_hash VARCHAR(100) NOT NULL,
+    customer_id INT UNIQUE REFERENCES customers(id) ON DELETE SET NULL,
+    roles TEXT[] NOT NULL DEFAULT '{customer}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    password_changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Only a hash of each reset token is kept, the token itself is only ever sent by mail.
+CREATE TABLE password_resets (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
+
+-- RSA keys signing the access tokens. A retired key no longer signs, but stays published in
+-- the JWKS until the tokens it signed have expired.
+CREATE TABLE signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMP
+);
+
+-- Refresh tokens are single use, each refresh replaces the token with a new one of the same family.
+-- Presenting a used token again revokes the whole family.
+CREATE TABLE refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    family_id UUID NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL DEFAULT '',
+    issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- A session is a login. Its id is the sid claim of the access tokens and the family of its refresh tokens.
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- API keys of unattended integrations. Only hashes of the secrets are kept; after a rotation the
+-- previous secret stays valid until previous_secret_expires_at.
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) UNIQUE NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    previous_secret_hash VARCHAR(64),
+    previous_secret_expires_at TIMESTAMP,
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL,
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    rotated_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45) NOT NULL DEFAULT '',
+    revoked_at TIMESTAMP
+);
+
+-- Token buckets of the rate limiter, when they are shared between instances.
+CREATE TABLE rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Version of this schema. Bump it together with data.SchemaVersion when the schema changes,
+-- so /readyz reports instances running against a database that wasn't migrated.
+CREATE TABLE schema_version (
+    version INT NOT NULL
+);
+
+INSERT INTO schema_version (version) VALUES (2);
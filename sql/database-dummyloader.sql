@@ -1298,106 +1298,106 @@ INSERT INTO customers (id, name, email, created_at, address_id) VALUES ('96', 'B
 INSERT INTO customers (id, name, email, created_at, address_id) VALUES ('97', 'Richard Barnett', 'iwong@example.net', '2022-10-28 04:43:20', '28');
 INSERT INTO customers (id, name, email, created_at, address_id) VALUES ('98', 'Christian Johnson', 'rraymond@example.org', '2023-06-27 13:18:26', '25');
 INSERT INTO customers (id, name, email, created_at, address_id) VALUES ('99', 'Elizabeth Oconnor', 'liunicholas@example.com', '2020-01-08 23:37:25', '56');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('0', '12', '53.275331211247035', '2021-06-22 13:36:20', 'pressure');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('1', '96', '14.290939446779273', '2020-12-05 02:38:01', 'up');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('2', '42', '61.45434965673066', '2022-06-16 21:25:07', 'popular');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('3', '72', '1.4689152019008436', '2022-01-31 09:50:15', 'cultural');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('4', '80', '8.549066386117044', '2020-05-25 05:27:03', 'structure');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('5', '78', '71.69440849477745', '2020-12-10 11:41:13', 'stock');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('6', '53', '96.29017438372556', '2024-02-20 15:04:53', 'want');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('7', '65', '72.19795432299343', '2022-06-28 00:48:36', 'speak');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('8', '98', '42.22216771828531', '2023-09-29 23:24:04', 'around');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('9', '77', '54.82206217693572', '2021-09-29 11:00:12', 'total');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('10', '13', '68.94723170639001', '2024-02-20 21:18:15', 'quickly');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('11', '53', '47.51249606783496', '2021-03-08 17:51:50', 'support');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('12', '1', '96.74084457786613', '2023-06-03 16:00:10', 'factor');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('13', '11', '15.018744245390351', '2022-01-23 07:56:35', 'science');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('14', '45', '39.0795194201245', '2023-03-28 03:55:26', 'economy');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('15', '50', '28.450422022232747', '2023-12-08 09:36:50', 'allow');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('16', '94', '79.02223380220457', '2020-03-06 00:27:03', 'set');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('17', '99', '44.55294733062481', '2023-05-21 17:15:24', 'instead');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('18', '14', '87.08263220655077', '2023-05-13 20:32:11', 'structure');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('19', '33', '75.04309292188489', '2024-07-28 05:47:04', 'public');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('20', '69', '38.10805154574424', '2022-05-28 20:16:07', 'education');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('21', '75', '31.82011124829702', '2023-02-04 03:15:49', 'little');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('22', '45', '78.25553748534162', '2022-04-11 14:15:33', 'what');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('23', '18', '41.72407378591874', '2020-12-15 03:32:41', 'save');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('24', '32', '85.89338522298364', '2021-05-12 12:31:11', 'large');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('25', '75', '8.853378554930988', '2021-08-31 12:21:24', 'information');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('26', '14', '53.78638234884913', '2021-02-02 15:25:59', 'its');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('27', '95', '91.96607490295229', '2022-12-14 23:42:10', 'rise');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('28', '22', '34.56559221998594', '2023-05-05 22:10:56', 'pick');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('29', '36', '39.5179580661297', '2021-06-17 03:11:52', 'wrong');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('30', '73', '66.79245103383259', '2023-12-01 17:58:46', 'population');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('31', '16', '40.60032021388863', '2024-02-20 04:48:11', 'matter');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('32', '59', '26.770217740221725', '2023-03-17 06:33:17', 'sign');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('33', '53', '20.300097457250967', '2021-08-05 06:54:40', 'month');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('34', '75', '98.26031576869464', '2024-06-20 00:05:54', 'building');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('35', '64', '26.159890367937276', '2021-06-10 14:50:45', 'spring');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('36', '40', '63.15398401489325', '2022-09-20 08:59:20', 'skin');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('37', '28', '68.62810675352448', '2023-12-01 14:05:32', 'such');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('38', '66', '39.197711110294684', '2024-09-26 08:53:20', 'most');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('39', '77', '78.13064591336588', '2020-07-08 12:06:43', 'ready');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('40', '68', '83.34223155830621', '2024-05-24 15:36:27', 'now');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('41', '37', '33.271949490747346', '2021-04-01 21:50:13', 'black');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('42', '50', '90.9988229164498', '2023-10-09 00:31:50', 'deal');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('43', '28', '60.474195801651575', '2022-08-12 02:17:16', 'necessary');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('44', '17', '24.405127485939865', '2022-03-26 17:03:38', 'protect');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('45', '13', '55.634658961335475', '2022-05-14 00:53:24', 'last');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('46', '23', '98.6433154516867', '2020-06-08 06:23:01', 'perform');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('47', '94', '7.442664642631085', '2022-03-23 08:02:28', 'chair');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('48', '17', '62.388154741437255', '2024-06-04 01:01:04', 'program');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('49', '81', '62.333741813745554', '2020-11-21 07:43:01', 'move');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('50', '31', '11.449789493725946', '2024-09-19 20:39:40', 'break');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('51', '29', '3.1053520659865113', '2022-09-02 21:30:18', 'maybe');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('52', '32', '92.10745336717764', '2020-08-18 11:35:30', 'summer');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('53', '16', '10.399527127620035', '2022-05-03 08:21:14', 'wish');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('54', '69', '73.6206366003845', '2021-12-11 05:03:11', 'media');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('55', '76', '30.25261194889598', '2021-03-16 12:12:05', 'word');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('56', '48', '61.56602321411571', '2023-03-30 08:53:47', 'write');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('57', '91', '4.365768248857369', '2021-11-28 11:18:51', 'town');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('58', '39', '53.53762708123589', '2020-07-14 05:00:57', 'ago');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('59', '44', '34.03752605579708', '2023-04-20 19:39:41', 'understand');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('60', '25', '96.28352055280928', '2024-02-09 21:58:55', 'top');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('61', '46', '37.31386996388913', '2022-06-02 03:24:56', 'Mr');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('62', '54', '82.78952710293039', '2020-07-23 13:13:50', 'employee');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('63', '54', '22.051110717447184', '2020-04-24 17:44:06', 'send');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('64', '98', '67.53366589759334', '2024-05-17 17:25:00', 'once');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('65', '32', '63.899188794173355', '2021-11-09 12:53:22', 'simple');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('66', '97', '88.20837000190726', '2021-11-18 02:07:39', 'rule');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('67', '53', '62.41006673597795', '2023-02-15 13:21:22', 'carry');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('68', '87', '40.93635725290622', '2021-06-02 02:59:34', 'impact');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('69', '96', '74.40561551950302', '2023-05-16 20:15:35', 'suddenly');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('70', '71', '15.466384258027912', '2020-01-04 22:49:21', 'film');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('71', '58', '40.95615900161414', '2020-09-02 17:23:14', 'face');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('72', '13', '38.96803278838519', '2021-11-13 20:51:16', 'culture');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('73', '93', '24.583445403535155', '2021-10-11 23:21:07', 'inside');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('74', '50', '24.477715129982432', '2021-06-22 09:52:22', 'dream');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('75', '33', '62.920029615079784', '2023-07-21 17:02:06', 'mother');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('76', '72', '41.607829849224316', '2020-11-27 05:55:14', 'voice');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('77', '67', '5.546161370908761', '2023-04-01 04:08:48', 'letter');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('78', '7', '20.138343039317483', '2022-10-13 14:38:51', 'method');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('79', '35', '68.11976439437028', '2020-07-05 01:32:43', 'something');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('80', '82', '42.37328287005552', '2023-01-01 08:33:35', 'benefit');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('81', '36', '70.80338719433315', '2021-08-31 00:38:31', 'mother');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('82', '65', '97.74091933014401', '2020-11-25 00:04:11', 'condition');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('83', '24', '57.30429405022204', '2021-07-02 13:16:38', 'she');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('84', '97', '51.54941528907999', '2022-07-20 04:58:26', 'could');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('85', '98', '36.85130514353062', '2020-07-17 16:24:26', 'individual');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('86', '96', '17.476998049829465', '2023-03-18 08:02:45', 'none');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('87', '67', '10.806267098315745', '2024-07-28 02:56:00', 'several');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('88', '81', '18.128835786398334', '2024-05-19 17:14:10', 'language');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('89', '87', '74.44382503945873', '2024-04-24 08:25:10', 'knowledge');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('90', '33', '81.0498767002169', '2022-11-02 03:59:42', 'that');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('91', '97', '62.16918787344463', '2024-01-21 00:37:56', 'nation');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('92', '2', '8.09444383882158', '2023-10-10 15:37:22', 'former');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('93', '27', '60.102123021284214', '2024-09-14 15:03:40', 'may');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('94', '45', '44.13554289380793', '2021-05-04 13:55:45', 'protect');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('95', '11', '27.167528509065175', '2021-11-20 02:09:22', 'dream');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('96', '80', '63.60918296202855', '2020-02-22 12:58:08', 'daughter');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('97', '66', '14.81316884436618', '2021-08-10 16:20:32', 'first');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('98', '79', '27.77856494667763', '2024-06-24 15:04:50', 'development');
-INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('99', '68', '10.646866704543394', '2020-02-24 21:09:43', 'prove');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('0', '12', '53.275331211247035', '2021-06-22 13:36:20', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('1', '96', '14.290939446779273', '2020-12-05 02:38:01', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('2', '42', '61.45434965673066', '2022-06-16 21:25:07', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('3', '72', '1.4689152019008436', '2022-01-31 09:50:15', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('4', '80', '8.549066386117044', '2020-05-25 05:27:03', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('5', '78', '71.69440849477745', '2020-12-10 11:41:13', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('6', '53', '96.29017438372556', '2024-02-20 15:04:53', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('7', '65', '72.19795432299343', '2022-06-28 00:48:36', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('8', '98', '42.22216771828531', '2023-09-29 23:24:04', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('9', '77', '54.82206217693572', '2021-09-29 11:00:12', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('10', '13', '68.94723170639001', '2024-02-20 21:18:15', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('11', '53', '47.51249606783496', '2021-03-08 17:51:50', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('12', '1', '96.74084457786613', '2023-06-03 16:00:10', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('13', '11', '15.018744245390351', '2022-01-23 07:56:35', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('14', '45', '39.0795194201245', '2023-03-28 03:55:26', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('15', '50', '28.450422022232747', '2023-12-08 09:36:50', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('16', '94', '79.02223380220457', '2020-03-06 00:27:03', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('17', '99', '44.55294733062481', '2023-05-21 17:15:24', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('18', '14', '87.08263220655077', '2023-05-13 20:32:11', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('19', '33', '75.04309292188489', '2024-07-28 05:47:04', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('20', '69', '38.10805154574424', '2022-05-28 20:16:07', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('21', '75', '31.82011124829702', '2023-02-04 03:15:49', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('22', '45', '78.25553748534162', '2022-04-11 14:15:33', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('23', '18', '41.72407378591874', '2020-12-15 03:32:41', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('24', '32', '85.89338522298364', '2021-05-12 12:31:11', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('25', '75', '8.853378554930988', '2021-08-31 12:21:24', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('26', '14', '53.78638234884913', '2021-02-02 15:25:59', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('27', '95', '91.96607490295229', '2022-12-14 23:42:10', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('28', '22', '34.56559221998594', '2023-05-05 22:10:56', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('29', '36', '39.5179580661297', '2021-06-17 03:11:52', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('30', '73', '66.79245103383259', '2023-12-01 17:58:46', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('31', '16', '40.60032021388863', '2024-02-20 04:48:11', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('32', '59', '26.770217740221725', '2023-03-17 06:33:17', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('33', '53', '20.300097457250967', '2021-08-05 06:54:40', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('34', '75', '98.26031576869464', '2024-06-20 00:05:54', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('35', '64', '26.159890367937276', '2021-06-10 14:50:45', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('36', '40', '63.15398401489325', '2022-09-20 08:59:20', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('37', '28', '68.62810675352448', '2023-12-01 14:05:32', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('38', '66', '39.197711110294684', '2024-09-26 08:53:20', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('39', '77', '78.13064591336588', '2020-07-08 12:06:43', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('40', '68', '83.34223155830621', '2024-05-24 15:36:27', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('41', '37', '33.271949490747346', '2021-04-01 21:50:13', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('42', '50', '90.9988229164498', '2023-10-09 00:31:50', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('43', '28', '60.474195801651575', '2022-08-12 02:17:16', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('44', '17', '24.405127485939865', '2022-03-26 17:03:38', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('45', '13', '55.634658961335475', '2022-05-14 00:53:24', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('46', '23', '98.6433154516867', '2020-06-08 06:23:01', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('47', '94', '7.442664642631085', '2022-03-23 08:02:28', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('48', '17', '62.388154741437255', '2024-06-04 01:01:04', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('49', '81', '62.333741813745554', '2020-11-21 07:43:01', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('50', '31', '11.449789493725946', '2024-09-19 20:39:40', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('51', '29', '3.1053520659865113', '2022-09-02 21:30:18', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('52', '32', '92.10745336717764', '2020-08-18 11:35:30', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('53', '16', '10.399527127620035', '2022-05-03 08:21:14', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('54', '69', '73.6206366003845', '2021-12-11 05:03:11', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('55', '76', '30.25261194889598', '2021-03-16 12:12:05', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('56', '48', '61.56602321411571', '2023-03-30 08:53:47', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('57', '91', '4.365768248857369', '2021-11-28 11:18:51', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('58', '39', '53.53762708123589', '2020-07-14 05:00:57', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('59', '44', '34.03752605579708', '2023-04-20 19:39:41', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('60', '25', '96.28352055280928', '2024-02-09 21:58:55', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('61', '46', '37.31386996388913', '2022-06-02 03:24:56', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('62', '54', '82.78952710293039', '2020-07-23 13:13:50', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('63', '54', '22.051110717447184', '2020-04-24 17:44:06', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('64', '98', '67.53366589759334', '2024-05-17 17:25:00', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('65', '32', '63.899188794173355', '2021-11-09 12:53:22', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('66', '97', '88.20837000190726', '2021-11-18 02:07:39', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('67', '53', '62.41006673597795', '2023-02-15 13:21:22', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('68', '87', '40.93635725290622', '2021-06-02 02:59:34', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('69', '96', '74.40561551950302', '2023-05-16 20:15:35', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('70', '71', '15.466384258027912', '2020-01-04 22:49:21', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('71', '58', '40.95615900161414', '2020-09-02 17:23:14', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('72', '13', '38.96803278838519', '2021-11-13 20:51:16', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('73', '93', '24.583445403535155', '2021-10-11 23:21:07', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('74', '50', '24.477715129982432', '2021-06-22 09:52:22', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('75', '33', '62.920029615079784', '2023-07-21 17:02:06', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('76', '72', '41.607829849224316', '2020-11-27 05:55:14', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('77', '67', '5.546161370908761', '2023-04-01 04:08:48', 'delivered');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('78', '7', '20.138343039317483', '2022-10-13 14:38:51', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('79', '35', '68.11976439437028', '2020-07-05 01:32:43', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('80', '82', '42.37328287005552', '2023-01-01 08:33:35', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('81', '36', '70.80338719433315', '2021-08-31 00:38:31', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('82', '65', '97.74091933014401', '2020-11-25 00:04:11', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('83', '24', '57.30429405022204', '2021-07-02 13:16:38', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('84', '97', '51.54941528907999', '2022-07-20 04:58:26', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('85', '98', '36.85130514353062', '2020-07-17 16:24:26', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('86', '96', '17.476998049829465', '2023-03-18 08:02:45', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('87', '67', '10.806267098315745', '2024-07-28 02:56:00', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('88', '81', '18.128835786398334', '2024-05-19 17:14:10', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('89', '87', '74.44382503945873', '2024-04-24 08:25:10', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('90', '33', '81.0498767002169', '2022-11-02 03:59:42', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('91', '97', '62.16918787344463', '2024-01-21 00:37:56', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('92', '2', '8.09444383882158', '2023-10-10 15:37:22', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('93', '27', '60.102123021284214', '2024-09-14 15:03:40', 'paid');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('94', '45', '44.13554289380793', '2021-05-04 13:55:45', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('95', '11', '27.167528509065175', '2021-11-20 02:09:22', 'cancelled');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('96', '80', '63.60918296202855', '2020-02-22 12:58:08', 'shipped');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('97', '66', '14.81316884436618', '2021-08-10 16:20:32', 'refunded');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('98', '79', '27.77856494667763', '2024-06-24 15:04:50', 'pending');
+INSERT INTO orders (id, customer_id, total_price, created_at, status) VALUES ('99', '68', '10.646866704543394', '2020-02-24 21:09:43', 'refunded');
 INSERT INTO order_items (id, order_id, book_id, quantity) VALUES ('0', '58', '426', '64');
 INSERT INTO order_items (id, order_id, book_id, quantity) VALUES ('1', '54', '562', '77');
 INSERT INTO order_items (id, order_id, book_id, quantity) VALUES ('2', '66', '833', '48');
@@ -1499,6 +1499,22 @@ INSERT INTO order_items (id, order_id, book_id, quantity) VALUES ('97', '55', '6
 INSERT INTO order_items (id, order_id, book_id, quantity) VALUES ('98', '82', '451', '16');
 INSERT INTO order_items (id, order_id, book_id, quantity) VALUES ('99', '0', '582', '24');
 
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT', 'Sweden', '', '', 25.00);
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT reduced rate', 'Sweden', '', 'books', 6.00);
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT reduced rate', 'Sweden', '', 'ebooks', 6.00);
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT', 'Norway', '', '', 25.00);
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT exempt', 'Norway', '', 'books', 0.00);
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT', 'Poland', '', '', 23.00);
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT reduced rate', 'Poland', '', 'books', 5.00);
+INSERT INTO tax_rules (name, country, state, category, rate) VALUES ('VAT reduced rate', 'Poland', '', 'ebooks', 5.00);
+
+INSERT INTO shipping_zones (name, countries, rate_basis, base_rate, unit_rate, free_shipping_threshold) VALUES ('Nordics', '{"Sweden", "Norway"}', 'items', 4.90, 1.00, 50.00);
+INSERT INTO shipping_zones (name, countries, rate_basis, base_rate, unit_rate, free_shipping_threshold) VALUES ('Europe', '{"Poland", "Monaco", "Ukraine", "Jersey"}', 'items', 6.90, 1.50, 75.00);
+INSERT INTO shipping_zones (name, countries, rate_basis, base_rate, unit_rate, free_shipping_threshold) VALUES ('Rest of the world', '{"*"}', 'weight', 12.00, 8.00, 0);
+
+-- Initial admin account, password "change-me-now". Change it after the first login.
+INSERT INTO users (email, name, password_hash, roles) VALUES ('admin@bookstore.local', 'Administrator', '$2a$10$KDxBMQ5yRTfqdwdnAs9TYOSII0HTN/Pa9hZbIxDzJmm835z.jnevy', '{admin}');
+
 CREATE OR REPLACE PROCEDURE sync_serial_sequence(table_name TEXT, column_name TEXT)
 LANGUAGE plpgsql
 AS $$
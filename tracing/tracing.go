@@ -0,0 +1,99 @@
+// Package tracing sets up OpenTelemetry tracing: the exporter, the W3C trace context propagation,
+// and the helpers shared by the HTTP and database spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ServiceName = "finalproject"
+
+// Exporters.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Setup installs the tracer provider exporting to exporter: "otlp" sends the spans over OTLP/HTTP
+// to OTEL_EXPORTER_OTLP_ENDPOINT (http://localhost:4318 by default), "stdout" prints them, and
+// "none" or "" keeps tracing off. The trace context is propagated in any case, so the traceparent
+// of a request is passed on even when this instance doesn't export. The returned function flushes
+// the pending spans and stops the exporter.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(strings.TrimSpace(exporter)) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout, "console":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected otlp, stdout or none", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the service name.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sampler is read from OTEL_TRACES_SAMPLER, every trace is sampled by default.
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of a package, a no-op one until Setup installs a provider.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(ServiceName + "/" + name)
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholder    = regexp.MustCompile(`\$\d+`)
+)
+
+// SanitizeSQL collapses the whitespace of a query and replaces its string and numeric literals
+// by ?, so spans never carry values. Placeholders ($1) are kept.
+func SanitizeSQL(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	query = stringLiteral.ReplaceAllString(query, "?")
+
+	// Placeholders are set aside while the numbers are replaced.
+	placeholders := placeholder.FindAllString(query, -1)
+	query = placeholder.ReplaceAllString(query, "$$\x00")
+	query = numericLiteral.ReplaceAllString(query, "?")
+	for _, p := range placeholders {
+		query = strings.Replace(query, "$\x00", p, 1)
+	}
+	return query
+}